@@ -2,7 +2,11 @@ package apispec
 
 import (
 	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
 
+	e "github.com/simplicity-load/apispec/pkg/errors"
 	generate "github.com/simplicity-load/apispec/pkg/gen"
 	"github.com/simplicity-load/apispec/pkg/gen/openapi"
 	"github.com/simplicity-load/apispec/pkg/http"
@@ -24,15 +28,88 @@ func Generate(config http.HttpServer) error {
 	if err != nil {
 		return fmt.Errorf("failed traversing paths: %w", err)
 	}
-	err = generate.Generate(repr.Representation{
-		Routes: paths,
-	}, config.OutputFile, config.ValidateUrl)
+	representation := repr.NewRepresentation(paths)
+
+	backend, err := serverBackend(config)
+	if err != nil {
+		return fmt.Errorf("failed selecting server backend: %w", err)
+	}
+	err = generate.GenerateWith(backend, representation, config.OutputFile, config.ValidateUrl)
 	if err != nil {
 		return fmt.Errorf("failed generating: %w", err)
 	}
+
+	if config.ClientOutputFile == nil {
+		return nil
+	}
+	client, err := clientTemplate(config)
+	if err != nil {
+		return fmt.Errorf("failed selecting client: %w", err)
+	}
+	pkg := config.ClientPackage
+	if pkg == "" {
+		pkg = "client"
+	}
+	err = generate.GenerateClient(client, representation, config.ClientOutputFile, pkg)
+	if err != nil {
+		return fmt.Errorf("failed generating client: %w", err)
+	}
 	return nil
 }
 
+// serverBackend resolves config.ServerTemplate to a registered backend or,
+// failing that, to a custom template extending the default backend.
+func serverBackend(config http.HttpServer) (generate.Backend, error) {
+	name := config.ServerTemplate
+	if name == "" {
+		name = generate.DefaultBackend
+	}
+	backend, lookupErr := generate.Lookup(name)
+	if lookupErr == nil {
+		return backend, nil
+	}
+
+	base, err := generate.Lookup(generate.DefaultBackend)
+	if err != nil {
+		return generate.Backend{}, err
+	}
+	fsys, name := templateFS(config.ServerTemplateFS, name)
+	backend, err = generate.FromFS(base, fsys, name)
+	if err != nil {
+		return generate.Backend{}, fmt.Errorf("%w, nor a template: %w", lookupErr, err)
+	}
+	return backend, nil
+}
+
+// clientTemplate resolves config.ClientTemplate to a registered client or,
+// failing that, to a custom template.
+func clientTemplate(config http.HttpServer) (generate.Client, error) {
+	name := config.ClientTemplate
+	if name == "" {
+		name = generate.DefaultClient
+	}
+	client, lookupErr := generate.LookupClient(name)
+	if lookupErr == nil {
+		return client, nil
+	}
+
+	fsys, name := templateFS(config.ServerTemplateFS, name)
+	client, err := generate.ClientFromFS(fsys, name)
+	if err != nil {
+		return generate.Client{}, fmt.Errorf("%w, nor a template: %w", lookupErr, err)
+	}
+	return client, nil
+}
+
+// templateFS falls back to reading name from the OS filesystem when fsys is
+// nil.
+func templateFS(fsys fs.FS, name string) (fs.FS, string) {
+	if fsys != nil {
+		return fsys, name
+	}
+	return os.DirFS(filepath.Dir(name)), filepath.Base(name)
+}
+
 func GenerateOpenAPI(config http.OpenAPIConfig) error {
 	// Parse the routes to get structured representation
 	paths, err := server.ParsePaths(config.Routes)
@@ -51,7 +128,14 @@ func GenerateOpenAPI(config http.OpenAPIConfig) error {
 	}
 
 	// Generate OpenAPI specification
-	err = openapi.Generate(paths, config.OutputFile, title, version, config.ServerURL)
+	switch config.Format {
+	case "", http.OpenAPIJSON:
+		err = openapi.Generate(paths, config.OutputFile, title, version, config.ServerURL)
+	case http.OpenAPIYAML:
+		err = openapi.GenerateYAML(paths, config.OutputFile, title, version, config.ServerURL)
+	default:
+		err = e.ErrBadValueFromList("format", config.Format, http.OpenAPIFormats)
+	}
 	if err != nil {
 		return fmt.Errorf("failed generating OpenAPI spec: %w", err)
 	}
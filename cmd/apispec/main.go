@@ -0,0 +1,116 @@
+// Command apispec generates servers, clients and OpenAPI documents from the
+// route tree of a Go package.
+//
+// The package must export a function returning the routes, e.g.
+//
+//	func Routes() *http.Path
+//
+// apispec writes a bootstrap program calling it into a temporary directory of
+// the current module and runs it with the given command, so paths are
+// relative to the working directory:
+//
+//	//go:generate go run github.com/simplicity-load/apispec/cmd/apispec -pkg . generate -server nethttp -o server.gen.go
+//	//go:generate go run github.com/simplicity-load/apispec/cmd/apispec -pkg . openapi -o openapi.json
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/simplicity-load/apispec/pkg/cli"
+)
+
+// Version is set at build time.
+var Version = "dev"
+
+func main() {
+	flag.Usage = usage
+	pkg := flag.String("pkg", ".", "package exporting the routes, as an import path or directory")
+	routes := flag.String("routes", "Routes", "function returning the routes")
+	keep := flag.Bool("keep", false, "keep the bootstrap program for debugging")
+	version := flag.Bool("version", false, "print the version")
+	flag.Parse()
+
+	if *version {
+		fmt.Println(Version)
+		return
+	}
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+	err := run(*pkg, *routes, *keep, flag.Args())
+	var exitErr *exec.ExitError
+	switch {
+	case errors.As(err, &exitErr):
+		// the bootstrap program reported the error already
+		os.Exit(exitErr.ExitCode())
+	case err != nil:
+		fmt.Fprintln(os.Stderr, "apispec:", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: apispec [flags] <command> [command flags]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Flags:")
+	flag.PrintDefaults()
+	fmt.Fprintln(os.Stderr)
+	cli.Usage(os.Stderr)
+}
+
+func run(pkg, routes string, keep bool, args []string) error {
+	importPath, err := goList(pkg)
+	if err != nil {
+		return err
+	}
+
+	// the bootstrap program must live in the current module to resolve its
+	// imports, the underscore keeps it out of ./... patterns
+	dir, err := os.MkdirTemp(".", "_apispec")
+	if err != nil {
+		return fmt.Errorf("failed creating bootstrap directory: %w", err)
+	}
+	if keep {
+		fmt.Fprintln(os.Stderr, "apispec: bootstrap program kept in", dir)
+	} else {
+		defer os.RemoveAll(dir)
+	}
+
+	f, err := os.Create(filepath.Join(dir, "main.go"))
+	if err != nil {
+		return fmt.Errorf("failed creating bootstrap program: %w", err)
+	}
+	err = cli.Bootstrap(f, importPath, routes)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed writing bootstrap program: %w", err)
+	}
+
+	cmd := exec.Command("go", append([]string{"run", "./" + filepath.ToSlash(dir)}, args...)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// goList resolves pkg, a directory or import path, to its import path.
+func goList(pkg string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("go", "list", "-f", "{{.ImportPath}}", pkg)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed resolving package %q: %w: %s", pkg, err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -75,12 +75,20 @@ let
     ];
   };
 
+  cli = customGoBuild {
+    name = "cli";
+    subPackages = [
+      "cmd/apispec"
+    ];
+  };
+
 in
 {
   default = api.docker;
 
   api-bin = api.binary;
   notify-bin = notify.binary;
+  cli-bin = cli.binary;
 
   api-docker = api.docker;
   notify-docker = notify.docker;
@@ -4,8 +4,11 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/simplicity-load/apispec"
 	"github.com/simplicity-load/apispec/pkg/http"
@@ -157,7 +160,7 @@ func TestGenerateOpenAPI_Comprehensive(t *testing.T) {
 			rb := postOp["requestBody"].(map[string]interface{})
 			content := rb["content"].(map[string]interface{})
 			jsonContent := content["application/json"].(map[string]interface{})
-			schema := jsonContent["schema"].(map[string]interface{})
+			schema := resolveRef(t, result, jsonContent["schema"])
 			props := schema["properties"].(map[string]interface{})
 
 			// Verify flattened/nested structure
@@ -167,7 +170,7 @@ func TestGenerateOpenAPI_Comprehensive(t *testing.T) {
 			if addr, ok := props["address"]; !ok {
 				t.Error("Missing 'address' nested struct in CreateUserRequest schema")
 			} else {
-				addrProps := addr.(map[string]interface{})["properties"].(map[string]interface{})
+				addrProps := resolveRef(t, result, addr)["properties"].(map[string]interface{})
 				if _, ok := addrProps["city"]; !ok {
 					t.Error("Missing 'city' field in nested Address schema")
 				}
@@ -222,7 +225,7 @@ func TestGenerateOpenAPI_Comprehensive(t *testing.T) {
 		okResp := responses["200"].(map[string]interface{})
 		content := okResp["content"].(map[string]interface{})
 		jsonContent := content["application/json"].(map[string]interface{})
-		schema := jsonContent["schema"].(map[string]interface{})
+		schema := resolveRef(t, result, jsonContent["schema"])
 
 		// Expect object type (ListUsersResponse struct)
 		if schema["type"] != "object" {
@@ -236,7 +239,44 @@ func TestGenerateOpenAPI_Comprehensive(t *testing.T) {
 		if usersProp["type"] != "array" {
 			t.Errorf("Expected 'users' property to be 'array', got '%v'", usersProp["type"])
 		}
+		items := usersProp["items"].(map[string]interface{})
+		if items["$ref"] != "#/components/schemas/User" {
+			t.Errorf("Expected 'users' items to reference User, got '%v'", items["$ref"])
+		}
+	}
+
+	// 4. Verify named types are emitted once as components
+	schemas := result["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+	for _, name := range []string{"User", "Address", "CreateUserRequest", "ListUsersResponse"} {
+		if _, ok := schemas[name]; !ok {
+			t.Errorf("Missing component schema %q, got: %v", name, getKeys(schemas))
+		}
+	}
+	for _, method := range []string{"get", "put"} {
+		op := idMap[method].(map[string]interface{})
+		content := op["responses"].(map[string]interface{})["200"].(map[string]interface{})["content"].(map[string]interface{})
+		schema := content["application/json"].(map[string]interface{})["schema"].(map[string]interface{})
+		if schema["$ref"] != "#/components/schemas/User" {
+			t.Errorf("Expected %s %s response to reference User, got %v", method, idPathKey, schema)
+		}
+	}
+}
+
+// resolveRef follows a "#/components/schemas/..." reference of schema
+func resolveRef(t *testing.T, spec map[string]interface{}, schema interface{}) map[string]interface{} {
+	t.Helper()
+	s := schema.(map[string]interface{})
+	ref, ok := s["$ref"].(string)
+	if !ok {
+		return s
+	}
+	name := strings.TrimPrefix(ref, "#/components/schemas/")
+	schemas := spec["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+	resolved, ok := schemas[name].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Unresolved reference %q", ref)
 	}
+	return resolved
 }
 
 func getKeys(m map[string]interface{}) []string {
@@ -246,3 +286,571 @@ func getKeys(m map[string]interface{}) []string {
 	}
 	return keys
 }
+
+var ErrUserNotFound = errors.New("user not found")
+
+type ConflictError struct{ ID string }
+
+func (e *ConflictError) Error() string { return "conflict on " + e.ID }
+
+func TestGenerateOpenAPI_ErrorResponses(t *testing.T) {
+	getUserHandler := func(ctx context.Context, req *GetUserRequest) (*User, error) { return nil, nil }
+	updateUserHandler := func(ctx context.Context, req *UpdateUserRequest) (*User, error) { return nil, nil }
+
+	api := http.NewAPI()
+	api.MapErrors(http.ErrorIs(ErrUserNotFound, 404))
+	userId := api.Static("users").Param("id")
+	userId.Get(getUserHandler, "Get user by ID")
+	userId.Put(updateUserHandler, "Update user by ID",
+		http.Errors(http.ErrorAs[*ConflictError](409)))
+
+	var output bytes.Buffer
+	err := apispec.GenerateOpenAPI(http.OpenAPIConfig{Routes: api, OutputFile: &output})
+	if err != nil {
+		t.Fatalf("GenerateOpenAPI failed: %v", err)
+	}
+	var spec map[string]interface{}
+	if err := json.Unmarshal(output.Bytes(), &spec); err != nil {
+		t.Fatalf("Output is not valid JSON: %v", err)
+	}
+
+	item := spec["paths"].(map[string]interface{})["/users/{id}"].(map[string]interface{})
+	responses := func(method string) map[string]interface{} {
+		return item[method].(map[string]interface{})["responses"].(map[string]interface{})
+	}
+
+	notFound, ok := responses("get")["404"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("GET /users/{id} missing 404 response, got: %v", getKeys(responses("get")))
+	}
+	if notFound["description"] != "user not found" {
+		t.Errorf("404 description mismatch, got: %v", notFound["description"])
+	}
+	if _, ok := responses("get")["409"]; ok {
+		t.Error("GET /users/{id} must not inherit the 409 response of PUT")
+	}
+
+	conflict, ok := responses("put")["409"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("PUT /users/{id} missing 409 response, got: %v", getKeys(responses("put")))
+	}
+	if conflict["description"] != "ConflictError" {
+		t.Errorf("409 description mismatch, got: %v", conflict["description"])
+	}
+	content, ok := conflict["content"].(map[string]interface{})["application/problem+json"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("409 response must be application/problem+json, got: %v", conflict["content"])
+	}
+	problem := resolveRef(t, spec, content["schema"])
+	for _, name := range []string{"type", "title", "status", "detail", "errors"} {
+		if _, ok := problem["properties"].(map[string]interface{})[name]; !ok {
+			t.Errorf("Problem schema missing %s property, got: %v", name, problem)
+		}
+	}
+	for _, status := range []string{"400", "500"} {
+		response, ok := responses("get")[status].(map[string]interface{})
+		if !ok {
+			t.Fatalf("GET /users/{id} missing %s response, got: %v", status, getKeys(responses("get")))
+		}
+		schema := response["content"].(map[string]interface{})["application/problem+json"].(map[string]interface{})["schema"]
+		if !reflect.DeepEqual(schema, content["schema"]) {
+			t.Errorf("%s response must share the Problem schema, got: %v", status, schema)
+		}
+	}
+	if _, ok := responses("put")["404"]; !ok {
+		t.Error("PUT /users/{id} missing inherited 404 response")
+	}
+}
+
+func TestGenerateOpenAPI_ConflictingErrorStatus(t *testing.T) {
+	getUserHandler := func(ctx context.Context, req *GetUserRequest) (*User, error) { return nil, nil }
+
+	api := http.NewAPI()
+	api.MapErrors(http.ErrorIs(ErrUserNotFound, 404), http.ErrorIs(ErrUserNotFound, 410))
+	api.Static("users").Param("id").Get(getUserHandler, "Get user by ID")
+
+	var output bytes.Buffer
+	err := apispec.GenerateOpenAPI(http.OpenAPIConfig{Routes: api, OutputFile: &output})
+	if err == nil {
+		t.Fatal("expected an error for a sentinel mapped to two statuses")
+	}
+}
+
+func TestGenerateOpenAPI_Security(t *testing.T) {
+	getUserHandler := func(ctx context.Context, req *GetUserRequest) (*User, error) { return nil, nil }
+	deleteUserHandler := func(ctx context.Context, req *GetUserRequest) (*EmptyResponse, error) { return nil, nil }
+
+	api := http.NewAPI()
+	userId := api.Static("users").Param("id")
+	userId.Get(getUserHandler, "Get user by ID")
+	userId.Delete(deleteUserHandler, "Delete user by ID", http.Authz("admin"))
+
+	var output bytes.Buffer
+	err := apispec.GenerateOpenAPI(http.OpenAPIConfig{Routes: api, OutputFile: &output})
+	if err != nil {
+		t.Fatalf("GenerateOpenAPI failed: %v", err)
+	}
+	var spec map[string]interface{}
+	if err := json.Unmarshal(output.Bytes(), &spec); err != nil {
+		t.Fatalf("Output is not valid JSON: %v", err)
+	}
+
+	schemes := spec["components"].(map[string]interface{})["securitySchemes"].(map[string]interface{})
+	if _, ok := schemes["bearerAuth"]; !ok {
+		t.Fatalf("securitySchemes missing bearerAuth, got: %v", getKeys(schemes))
+	}
+
+	item := spec["paths"].(map[string]interface{})["/users/{id}"].(map[string]interface{})
+	if _, ok := item["get"].(map[string]interface{})["security"]; ok {
+		t.Error("GET /users/{id} must not require security")
+	}
+	deleteOp := item["delete"].(map[string]interface{})
+	security, ok := deleteOp["security"].([]interface{})
+	if !ok || len(security) != 1 {
+		t.Fatalf("DELETE /users/{id} security mismatch, got: %v", deleteOp["security"])
+	}
+	scopes := security[0].(map[string]interface{})["bearerAuth"].([]interface{})
+	if len(scopes) != 1 || scopes[0] != "admin" {
+		t.Errorf("DELETE /users/{id} scopes mismatch, got: %v", scopes)
+	}
+	responses := deleteOp["responses"].(map[string]interface{})
+	for _, status := range []string{"401", "403"} {
+		if _, ok := responses[status]; !ok {
+			t.Errorf("DELETE /users/{id} missing %s response", status)
+		}
+	}
+}
+
+type Comment struct {
+	Text    string    `json:"text"`
+	Replies []Comment `json:"replies"`
+}
+
+type Thread struct {
+	Root Comment `json:"root"`
+}
+
+func TestGenerateOpenAPI_RecursiveTypes(t *testing.T) {
+	getThreadHandler := func(ctx context.Context, req *GetUserRequest) (*Thread, error) { return nil, nil }
+
+	api := http.NewAPI()
+	api.Static("threads").Param("id").Get(getThreadHandler, "Get thread by ID")
+
+	var output bytes.Buffer
+	err := apispec.GenerateOpenAPI(http.OpenAPIConfig{Routes: api, OutputFile: &output})
+	if err != nil {
+		t.Fatalf("GenerateOpenAPI failed: %v", err)
+	}
+	var spec map[string]interface{}
+	if err := json.Unmarshal(output.Bytes(), &spec); err != nil {
+		t.Fatalf("Output is not valid JSON: %v", err)
+	}
+
+	schemas := spec["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+	comment, ok := schemas["Comment"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Comment schema missing, got: %v", getKeys(schemas))
+	}
+	replies := comment["properties"].(map[string]interface{})["replies"].(map[string]interface{})
+	items := replies["items"].(map[string]interface{})
+	if items["$ref"] != "#/components/schemas/Comment" {
+		t.Errorf("Comment.replies items must reference Comment, got: %v", items)
+	}
+	root := schemas["Thread"].(map[string]interface{})["properties"].(map[string]interface{})["root"]
+	if root.(map[string]interface{})["$ref"] != "#/components/schemas/Comment" {
+		t.Errorf("Thread.root must reference Comment, got: %v", root)
+	}
+}
+
+type UUID [16]byte
+
+func (u UUID) MarshalText() ([]byte, error) { return nil, nil }
+
+type ListEventsRequest struct {
+	Since   time.Time     `json:"since" as:"since,query"`
+	Timeout time.Duration `json:"timeout" as:"timeout,query"`
+}
+
+type Event struct {
+	ID      UUID          `json:"id"`
+	At      time.Time     `json:"at"`
+	Timeout time.Duration `json:"timeout"`
+}
+
+func TestGenerateOpenAPI_ScalarFormats(t *testing.T) {
+	listEventsHandler := func(ctx context.Context, req *ListEventsRequest) (*Event, error) { return nil, nil }
+
+	api := http.NewAPI()
+	api.Static("events").Get(listEventsHandler, "List events")
+
+	var output bytes.Buffer
+	err := apispec.GenerateOpenAPI(http.OpenAPIConfig{Routes: api, OutputFile: &output})
+	if err != nil {
+		t.Fatalf("GenerateOpenAPI failed: %v", err)
+	}
+	var spec map[string]interface{}
+	if err := json.Unmarshal(output.Bytes(), &spec); err != nil {
+		t.Fatalf("Output is not valid JSON: %v", err)
+	}
+
+	schemas := spec["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+	props := schemas["Event"].(map[string]interface{})["properties"].(map[string]interface{})
+	for name, want := range map[string][2]string{
+		"id":      {"string", "uuid"},
+		"at":      {"string", "date-time"},
+		"timeout": {"integer", "int64"},
+	} {
+		prop := props[name].(map[string]interface{})
+		if prop["type"] != want[0] || prop["format"] != want[1] {
+			t.Errorf("Event.%s mismatch, got: %v, wanted: %v", name, prop, want)
+		}
+	}
+
+	getOp := spec["paths"].(map[string]interface{})["/events"].(map[string]interface{})["get"].(map[string]interface{})
+	for _, param := range getOp["parameters"].([]interface{}) {
+		p := param.(map[string]interface{})
+		schema := p["schema"].(map[string]interface{})
+		if schema["type"] != "string" {
+			t.Errorf("parameter %s must be a string, got: %v", p["name"], schema)
+		}
+	}
+}
+
+type SessionRequest struct {
+	Token   string `json:"-" as:"x-token,header" validate:"required"`
+	Session string `json:"-" as:"session,cookie"`
+	Name    string `json:"name"`
+}
+
+type SessionResponse struct {
+	RequestID string `json:"-" as:"x-request-id,header"`
+	Session   string `json:"-" as:"session,cookie"`
+	Theme     string `json:"-" as:"theme,cookie"`
+	Name      string `json:"name"`
+}
+
+func TestGenerateOpenAPI_HeadersAndCookies(t *testing.T) {
+	loginHandler := func(ctx context.Context, req *SessionRequest) (*SessionResponse, error) { return nil, nil }
+
+	api := http.NewAPI()
+	api.Static("login").Post(loginHandler, "Log in")
+
+	var output bytes.Buffer
+	err := apispec.GenerateOpenAPI(http.OpenAPIConfig{Routes: api, OutputFile: &output})
+	if err != nil {
+		t.Fatalf("GenerateOpenAPI failed: %v", err)
+	}
+	var spec map[string]interface{}
+	if err := json.Unmarshal(output.Bytes(), &spec); err != nil {
+		t.Fatalf("Output is not valid JSON: %v", err)
+	}
+
+	op := spec["paths"].(map[string]interface{})["/login"].(map[string]interface{})["post"].(map[string]interface{})
+	params := op["parameters"].([]interface{})
+	if len(params) != 2 {
+		t.Fatalf("want 2 parameters, got: %v", params)
+	}
+	for i, want := range []struct {
+		name, in string
+		required bool
+	}{
+		{"x-token", "header", true},
+		{"session", "cookie", false},
+	} {
+		param := params[i].(map[string]interface{})
+		required, _ := param["required"].(bool)
+		if param["name"] != want.name || param["in"] != want.in || required != want.required {
+			t.Errorf("parameter %d mismatch, want %+v, got: %v", i, want, param)
+		}
+	}
+
+	headers := op["responses"].(map[string]interface{})["200"].(map[string]interface{})["headers"].(map[string]interface{})
+	if len(headers) != 2 {
+		t.Fatalf("want 2 response headers, got: %v", getKeys(headers))
+	}
+	if _, ok := headers["x-request-id"]; !ok {
+		t.Errorf("response headers missing x-request-id, got: %v", getKeys(headers))
+	}
+	setCookie, ok := headers["Set-Cookie"].(map[string]interface{})
+	if !ok || !strings.Contains(setCookie["description"].(string), "session, theme") {
+		t.Errorf("response headers must document the cookies in Set-Cookie, got: %v", headers["Set-Cookie"])
+	}
+}
+
+type SignupRequest struct {
+	Email    string   `json:"email" validate:"required,email"`
+	Website  string   `json:"website" validate:"omitempty,url"`
+	Username string   `json:"username" validate:"min=3,max=20"`
+	Code     string   `json:"code" validate:"len=6"`
+	Age      int      `json:"age" validate:"gte=18,lt=130"`
+	Plan     string   `json:"plan" validate:"oneof=free pro 'pro plus'"`
+	Level    int      `json:"level" validate:"oneof=1 2 3"`
+	Tags     []string `json:"tags" validate:"max=5,dive,min=2,uuid"`
+	Referrer string   `json:"referrer" validate:"required_with=Code|uuid"`
+	Tier     string   `json:"tier" validate:"oneof=a b|eq=c"`
+	Invites  []string `json:"invites" validate:"dive,required,email"`
+	Promos   []string `json:"promos" validate:"required,dive,len=6"`
+}
+
+func TestGenerateOpenAPI_ValidationConstraints(t *testing.T) {
+	signupHandler := func(ctx context.Context, req *SignupRequest) (*EmptyResponse, error) { return nil, nil }
+
+	api := http.NewAPI()
+	api.Static("signup").Post(signupHandler, "Sign up")
+
+	var output bytes.Buffer
+	err := apispec.GenerateOpenAPI(http.OpenAPIConfig{Routes: api, OutputFile: &output})
+	if err != nil {
+		t.Fatalf("GenerateOpenAPI failed: %v", err)
+	}
+	var spec map[string]interface{}
+	if err := json.Unmarshal(output.Bytes(), &spec); err != nil {
+		t.Fatalf("Output is not valid JSON: %v", err)
+	}
+
+	schema := spec["components"].(map[string]interface{})["schemas"].(map[string]interface{})["SignupRequest"].(map[string]interface{})
+	props := schema["properties"].(map[string]interface{})
+	for name, want := range map[string]string{
+		"email":    `{"format":"email","type":"string"}`,
+		"website":  `{"format":"uri","type":"string"}`,
+		"username": `{"maxLength":20,"minLength":3,"type":"string"}`,
+		"code":     `{"maxLength":6,"minLength":6,"type":"string"}`,
+		"age":      `{"exclusiveMaximum":130,"minimum":18,"type":"integer"}`,
+		"plan":     `{"enum":["free","pro","pro plus"],"type":"string"}`,
+		"level":    `{"enum":[1,2,3],"type":"integer"}`,
+		"tags":     `{"items":{"format":"uuid","minLength":2,"type":"string"},"maxItems":5,"type":"array"}`,
+		"referrer": `{"type":"string"}`,
+		"tier":     `{"type":"string"}`,
+		"invites":  `{"items":{"format":"email","type":"string"},"type":"array"}`,
+		"promos":   `{"items":{"maxLength":6,"minLength":6,"type":"string"},"type":"array"}`,
+	} {
+		got, err := json.Marshal(props[name])
+		if err != nil {
+			t.Fatalf("marshal %s failed: %v", name, err)
+		}
+		if string(got) != want {
+			t.Errorf("%s schema mismatch\nwant: %s\n got: %s", name, want, got)
+		}
+	}
+	// required after dive only applies to the items
+	if got, want := schema["required"], []interface{}{"email", "promos"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("required mismatch, got: %v, want: %v", got, want)
+	}
+}
+
+type Inventory struct {
+	Flags  map[string]bool        `json:"flags"`
+	Counts map[int]int            `json:"counts"`
+	Owners map[int64]Address      `json:"owners"`
+	Groups map[string][]string    `json:"groups"`
+	Nested map[string]map[int]int `json:"nested"`
+	Labels map[string]string      `json:"labels" validate:"max=10,dive,keys,min=2,endkeys,max=64"`
+}
+
+func TestGenerateOpenAPI_Maps(t *testing.T) {
+	getInventoryHandler := func(ctx context.Context, req *GetUserRequest) (*Inventory, error) { return nil, nil }
+
+	api := http.NewAPI()
+	api.Static("inventory").Param("id").Get(getInventoryHandler, "Get inventory")
+
+	var output bytes.Buffer
+	err := apispec.GenerateOpenAPI(http.OpenAPIConfig{Routes: api, OutputFile: &output})
+	if err != nil {
+		t.Fatalf("GenerateOpenAPI failed: %v", err)
+	}
+	var spec map[string]interface{}
+	if err := json.Unmarshal(output.Bytes(), &spec); err != nil {
+		t.Fatalf("Output is not valid JSON: %v", err)
+	}
+
+	schemas := spec["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+	props := schemas["Inventory"].(map[string]interface{})["properties"].(map[string]interface{})
+	for name, want := range map[string]string{
+		"flags":  `{"additionalProperties":{"type":"boolean"},"type":"object"}`,
+		"counts": `{"additionalProperties":{"type":"integer"},"propertyNames":{"pattern":"^-?[0-9]+$","type":"string"},"type":"object"}`,
+		"owners": `{"additionalProperties":{"$ref":"#/components/schemas/Address"},"propertyNames":{"pattern":"^-?[0-9]+$","type":"string"},"type":"object"}`,
+		"groups": `{"additionalProperties":{"items":{"type":"string"},"type":"array"},"type":"object"}`,
+		"nested": `{"additionalProperties":{"additionalProperties":{"type":"integer"},"propertyNames":{"pattern":"^-?[0-9]+$","type":"string"},"type":"object"},"type":"object"}`,
+		"labels": `{"additionalProperties":{"maxLength":64,"type":"string"},"maxProperties":10,"propertyNames":{"minLength":2,"type":"string"},"type":"object"}`,
+	} {
+		got, err := json.Marshal(props[name])
+		if err != nil {
+			t.Fatalf("marshal %s failed: %v", name, err)
+		}
+		if string(got) != want {
+			t.Errorf("%s schema mismatch\nwant: %s\n got: %s", name, want, got)
+		}
+	}
+	if _, ok := schemas["Address"]; !ok {
+		t.Errorf("map values must be emitted as components, got: %v", getKeys(schemas))
+	}
+}
+
+func TestGenerateOpenAPI_SuccessStatus(t *testing.T) {
+	createUserHandler := func(ctx context.Context, req *CreateUserRequest) (*User, error) { return nil, nil }
+	deleteUserHandler := func(ctx context.Context, req *GetUserRequest) (*EmptyResponse, error) { return nil, nil }
+
+	api := http.NewAPI()
+	users := api.Static("users")
+	users.Post(createUserHandler, "Create user", http.Status(201))
+	users.Param("id").Delete(deleteUserHandler, "Delete user by ID")
+
+	var output bytes.Buffer
+	err := apispec.GenerateOpenAPI(http.OpenAPIConfig{Routes: api, OutputFile: &output})
+	if err != nil {
+		t.Fatalf("GenerateOpenAPI failed: %v", err)
+	}
+	var spec map[string]interface{}
+	if err := json.Unmarshal(output.Bytes(), &spec); err != nil {
+		t.Fatalf("Output is not valid JSON: %v", err)
+	}
+	paths := spec["paths"].(map[string]interface{})
+	responses := func(path, method string) map[string]interface{} {
+		return paths[path].(map[string]interface{})[method].(map[string]interface{})["responses"].(map[string]interface{})
+	}
+
+	created, ok := responses("/users", "post")["201"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("POST /users missing 201 response, got: %v", getKeys(responses("/users", "post")))
+	}
+	if _, ok := created["content"].(map[string]interface{})["application/json"]; !ok {
+		t.Errorf("201 response must carry the body, got: %v", created)
+	}
+	if _, ok := responses("/users", "post")["200"]; ok {
+		t.Error("POST /users must not document a 200 response")
+	}
+
+	noContent, ok := responses("/users/{id}", "delete")["204"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("DELETE /users/{id} missing 204 response, got: %v", getKeys(responses("/users/{id}", "delete")))
+	}
+	if _, ok := noContent["content"]; ok {
+		t.Errorf("204 response must have no content, got: %v", noContent)
+	}
+}
+
+func TestGenerateOpenAPI_Methods(t *testing.T) {
+	getUserHandler := func(ctx context.Context, req *GetUserRequest) (*User, error) { return nil, nil }
+	traceHandler := func(ctx context.Context, req *GetUserRequest) (*EmptyResponse, error) { return nil, nil }
+
+	api := http.NewAPI()
+	id := api.Static("users").Param("id")
+	id.Get(getUserHandler, "Get user by ID", http.AutoHead())
+	id.Options(traceHandler, "Describe user")
+	id.Handle(http.TRACE, traceHandler, "Trace user")
+	id.Handle("PURGE", traceHandler, "Purge user")
+
+	var output bytes.Buffer
+	err := apispec.GenerateOpenAPI(http.OpenAPIConfig{Routes: api, OutputFile: &output})
+	if err != nil {
+		t.Fatalf("GenerateOpenAPI failed: %v", err)
+	}
+	var spec map[string]interface{}
+	if err := json.Unmarshal(output.Bytes(), &spec); err != nil {
+		t.Fatalf("Output is not valid JSON: %v", err)
+	}
+	item := spec["paths"].(map[string]interface{})["/users/{id}"].(map[string]interface{})
+	if got, want := getKeys(item), []string{"get", "head", "options", "trace", "x-additionalOperations"}; len(got) != len(want) {
+		t.Fatalf("path item methods mismatch, got: %v, want: %v", got, want)
+	}
+	additional := item["x-additionalOperations"].(map[string]interface{})
+	if purge, ok := additional["PURGE"].(map[string]interface{}); !ok || purge["description"] != "Purge user" {
+		t.Errorf("custom methods must be documented as extensions, got: %v", additional)
+	}
+	head := item["head"].(map[string]interface{})
+	getID := item["get"].(map[string]interface{})["operationId"]
+	if head["operationId"] != getID.(string)+"Head" {
+		t.Errorf("HEAD must have its own operation ID, got: %v", head["operationId"])
+	}
+	success, ok := head["responses"].(map[string]interface{})["200"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("HEAD missing 200 response, got: %v", head["responses"])
+	}
+	if _, ok := success["content"]; ok {
+		t.Errorf("HEAD responses must have no content, got: %v", success)
+	}
+}
+
+func TestGenerateOpenAPI_Wildcards(t *testing.T) {
+	type FileRequest struct {
+		Path string `json:"-" as:"path,path"`
+	}
+	getFileHandler := func(ctx context.Context, req *FileRequest) (*EmptyResponse, error) { return nil, nil }
+
+	api := http.NewAPI()
+	api.Static("files").Wildcard("path").Get(getFileHandler, "Get file")
+
+	var output bytes.Buffer
+	err := apispec.GenerateOpenAPI(http.OpenAPIConfig{Routes: api, OutputFile: &output})
+	if err != nil {
+		t.Fatalf("GenerateOpenAPI failed: %v", err)
+	}
+	var spec map[string]interface{}
+	if err := json.Unmarshal(output.Bytes(), &spec); err != nil {
+		t.Fatalf("Output is not valid JSON: %v", err)
+	}
+	item, ok := spec["paths"].(map[string]interface{})["/files/{path}"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("wildcard must be documented as a path parameter, got: %v", getKeys(spec["paths"].(map[string]interface{})))
+	}
+	params := item["get"].(map[string]interface{})["parameters"].([]interface{})
+	if len(params) != 1 {
+		t.Fatalf("want 1 parameter, got: %v", params)
+	}
+	param := params[0].(map[string]interface{})
+	if param["name"] != "path" || param["in"] != "path" || param["required"] != true || param["description"] == nil {
+		t.Errorf("wildcard parameter mismatch, got: %v", param)
+	}
+	if schema := param["schema"].(map[string]interface{}); schema["type"] != "string" {
+		t.Errorf("wildcard must be a string, got: %v", schema)
+	}
+}
+
+type Member struct {
+	ID    string `json:"-" as:"id,path"`
+	Token string `json:"-" as:"x-token,header"`
+	Name  string `json:"name"`
+	Notes string `json:"-"`
+}
+
+type Members struct {
+	Members []Member `json:"members"`
+}
+
+func TestGenerateOpenAPI_ComponentsIgnoreOrder(t *testing.T) {
+	listHandler := func(ctx context.Context, req *EmptyResponse) (*Members, error) { return nil, nil }
+	getHandler := func(ctx context.Context, req *Member) (*Member, error) { return nil, nil }
+
+	for name, declare := range map[string]func(p *http.Path){
+		"list first": func(p *http.Path) {
+			p.Get(listHandler, "List members")
+			p.Param("id").Get(getHandler, "Get member")
+		},
+		"get first": func(p *http.Path) {
+			p.Param("id").Get(getHandler, "Get member")
+			p.Get(listHandler, "List members")
+		},
+	} {
+		api := http.NewAPI()
+		declare(api.Static("members"))
+
+		var output bytes.Buffer
+		err := apispec.GenerateOpenAPI(http.OpenAPIConfig{Routes: api, OutputFile: &output})
+		if err != nil {
+			t.Fatalf("%s: GenerateOpenAPI failed: %v", name, err)
+		}
+		var spec map[string]interface{}
+		if err := json.Unmarshal(output.Bytes(), &spec); err != nil {
+			t.Fatalf("%s: Output is not valid JSON: %v", name, err)
+		}
+		schemas := spec["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+		member, ok := schemas["Member"].(map[string]interface{})
+		if !ok {
+			t.Fatalf("%s: Member component missing, got: %v", name, getKeys(schemas))
+		}
+		props := member["properties"].(map[string]interface{})
+		if got := getKeys(props); len(got) != 1 || got[0] != "name" {
+			t.Errorf("%s: Member must only document its JSON fields, got: %v", name, got)
+		}
+	}
+}
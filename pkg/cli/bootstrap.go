@@ -0,0 +1,40 @@
+package cli
+
+import (
+	"go/token"
+	"io"
+	"text/template"
+
+	e "github.com/simplicity-load/apispec/pkg/errors"
+)
+
+var bootstrapTempl = template.Must(template.New("bootstrap").Parse(`// Code generated by apispec. DO NOT EDIT.
+
+package main
+
+import (
+	"github.com/simplicity-load/apispec/pkg/cli"
+
+	routes "{{ .Import }}"
+)
+
+func main() {
+	cli.Main(routes.{{ .Routes }}())
+}
+`))
+
+// Bootstrap writes a main package running [Main] on the route tree returned
+// by the function routes, of signature func() *http.Path, of the package at
+// importPath.
+func Bootstrap(w io.Writer, importPath, routes string) error {
+	if !token.IsIdentifier(routes) || !token.IsExported(routes) {
+		return e.ErrBadValue("routes function", routes, "exported identifier")
+	}
+	return bootstrapTempl.Execute(w, struct {
+		Import string
+		Routes string
+	}{
+		Import: importPath,
+		Routes: routes,
+	})
+}
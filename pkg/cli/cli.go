@@ -0,0 +1,287 @@
+// Package cli implements the apispec subcommands over a route tree. It's run
+// by the bootstrap program cmd/apispec generates for a user package, and may
+// be called from a hand written main as well.
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/simplicity-load/apispec"
+	e "github.com/simplicity-load/apispec/pkg/errors"
+	"github.com/simplicity-load/apispec/pkg/http"
+	"github.com/simplicity-load/apispec/pkg/parse/server"
+	repr "github.com/simplicity-load/apispec/pkg/repr/http"
+)
+
+type command struct {
+	name  string
+	usage string
+	run   func(routes *http.Path, args []string, stdout io.Writer) error
+}
+
+var commands = []command{
+	{"generate", "write the server, and optionally the client, code", runGenerate},
+	{"openapi", "write the OpenAPI document", runOpenAPI},
+	{"routes", "print the route table", runRoutes},
+	{"lint", "check the API for mistakes", runLint},
+}
+
+var (
+	ErrNoCommand = errors.New("no command given")
+	ErrLint      = errors.New("lint failed")
+)
+
+// Run executes the subcommand named by args[0] on routes.
+func Run(routes *http.Path, args []string, stdout, stderr io.Writer) error {
+	if len(args) == 0 {
+		Usage(stderr)
+		return ErrNoCommand
+	}
+	i := slices.IndexFunc(commands, func(c command) bool { return c.name == args[0] })
+	if i < 0 {
+		Usage(stderr)
+		return e.ErrBadValueFromList("command", args[0], commandNames())
+	}
+	return commands[i].run(routes, args[1:], stdout)
+}
+
+// Main runs [Run] with the process arguments and exits on failure.
+func Main(routes *http.Path) {
+	if err := Run(routes, os.Args[1:], os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, "apispec:", err)
+		os.Exit(1)
+	}
+}
+
+// Usage prints the subcommands to w.
+func Usage(w io.Writer) {
+	fmt.Fprintln(w, "Commands:")
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, c := range commands {
+		fmt.Fprintf(tw, "  %s\t%s\n", c.name, c.usage)
+	}
+	tw.Flush()
+}
+
+func commandNames() []string {
+	names := make([]string, len(commands))
+	for i, c := range commands {
+		names[i] = c.name
+	}
+	return names
+}
+
+func runGenerate(routes *http.Path, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("generate", flag.ContinueOnError)
+	output := fs.String("o", "-", "server output file, - for stdout")
+	backend := fs.String("server", "", "server backend or template path (default fiber)")
+	validate := fs.String("validate", "github.com/go-playground/validator/v10", "import path of the validator")
+	clientOutput := fs.String("client-o", "", "client output file, none is written when empty")
+	client := fs.String("client", "", "client name or template path (default go)")
+	clientPackage := fs.String("client-package", "", "package name of the client (default client)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var out, clientOut bytes.Buffer
+	config := http.HttpServer{
+		Routes:         routes,
+		OutputFile:     &out,
+		ValidateUrl:    *validate,
+		ServerTemplate: *backend,
+		ClientTemplate: *client,
+		ClientPackage:  *clientPackage,
+	}
+	if *clientOutput != "" {
+		config.ClientOutputFile = &clientOut
+	}
+	if err := apispec.Generate(config); err != nil {
+		return err
+	}
+	if err := writeOutput(*output, out.Bytes(), stdout); err != nil {
+		return err
+	}
+	if *clientOutput == "" {
+		return nil
+	}
+	return writeOutput(*clientOutput, clientOut.Bytes(), stdout)
+}
+
+func runOpenAPI(routes *http.Path, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("openapi", flag.ContinueOnError)
+	output := fs.String("o", "-", "output file, - for stdout")
+	title := fs.String("title", "", "title of the API")
+	version := fs.String("version", "", "version of the API")
+	serverURL := fs.String("server-url", "", "URL the API is served at")
+	format := fs.String("format", "", "json or yaml (default yaml for .yaml and .yml outputs, json otherwise)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *format == "" {
+		*format = string(http.OpenAPIJSON)
+		if ext := filepath.Ext(*output); ext == ".yaml" || ext == ".yml" {
+			*format = string(http.OpenAPIYAML)
+		}
+	}
+
+	var out bytes.Buffer
+	err := apispec.GenerateOpenAPI(http.OpenAPIConfig{
+		Routes:     routes,
+		OutputFile: &out,
+		Title:      *title,
+		Version:    *version,
+		ServerURL:  *serverURL,
+		Format:     http.OpenAPIFormat(*format),
+	})
+	if err != nil {
+		return err
+	}
+	return writeOutput(*output, out.Bytes(), stdout)
+}
+
+func runRoutes(routes *http.Path, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("routes", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	paths, err := server.ParsePaths(routes)
+	if err != nil {
+		return fmt.Errorf("failed parsing paths: %w", err)
+	}
+	tw := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "METHOD\tPATH\tHANDLER")
+	for endpoint := range paths.AllEndpoints() {
+		path, err := routePath(endpoint)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", endpoint.Method, path, handlerName(endpoint.Handler))
+	}
+	return tw.Flush()
+}
+
+func runLint(routes *http.Path, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("lint", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	findings, err := Lint(routes)
+	var routeErrs server.RouteErrors
+	if errors.As(err, &routeErrs) {
+		for _, routeErr := range routeErrs {
+			fmt.Fprintln(stdout, routeErr)
+		}
+		return fmt.Errorf("%w: %d route errors", ErrLint, len(routeErrs))
+	}
+	if err != nil {
+		return err
+	}
+	for _, finding := range findings {
+		fmt.Fprintln(stdout, finding)
+	}
+	if len(findings) > 0 {
+		return fmt.Errorf("%w: %d findings", ErrLint, len(findings))
+	}
+	return nil
+}
+
+// Finding is a mistake found by [Lint].
+type Finding struct {
+	Method  http.Method
+	Path    string
+	Handler string
+	Message string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s %s (%s): %s", f.Method, f.Path, f.Handler, f.Message)
+}
+
+// Lint parses routes, failing on anything generation would fail on, and
+// reports endpoints that generate but are likely mistakes.
+func Lint(routes *http.Path) ([]Finding, error) {
+	paths, err := server.ParsePaths(routes)
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing paths: %w", err)
+	}
+
+	findings := make([]Finding, 0)
+	for endpoint := range paths.AllEndpoints() {
+		path, err := routePath(endpoint)
+		if err != nil {
+			return nil, err
+		}
+		finding := func(msg string) Finding {
+			return Finding{
+				Method:  endpoint.Method,
+				Path:    path,
+				Handler: handlerName(endpoint.Handler),
+				Message: msg,
+			}
+		}
+		if strings.TrimSpace(endpoint.Description) == "" {
+			findings = append(findings, finding("missing description"))
+		}
+		if endpoint.Method == http.GET && len(jsonFields(endpoint.Body)) > 0 {
+			findings = append(findings, finding("GET request has JSON body fields, they're never decoded"))
+		}
+	}
+	return findings, nil
+}
+
+// jsonFields lists the fields of data serialized in the JSON body.
+func jsonFields(data *repr.Data) []*repr.StructField {
+	fields := make([]*repr.StructField, 0)
+	for _, field := range data.Fields {
+		if field.Serialization != nil &&
+			field.Serialization.Type == repr.SerializationJSON &&
+			field.Serialization.Name != "-" {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+func routePath(endpoint *repr.Endpoint) (string, error) {
+	path, err := repr.PathToPattern(endpoint.Path)
+	if err != nil {
+		return "", err
+	}
+	if path == "" {
+		return "/", nil
+	}
+	return path, nil
+}
+
+func handlerName(h *repr.Handler) string {
+	if h.Reciever != nil {
+		recv := h.Reciever.Name
+		if h.Reciever.Pointer {
+			recv = "*" + recv
+		}
+		return fmt.Sprintf("(%s.%s).%s", h.Import, recv, h.Name)
+	}
+	return h.Import + "." + h.Name
+}
+
+// writeOutput writes what was generated to name, stdout for "-". Outputs are
+// only written once generation succeeded, so a failure leaves the previous
+// file in place.
+func writeOutput(name string, generated []byte, stdout io.Writer) error {
+	if name == "-" {
+		_, err := stdout.Write(generated)
+		return err
+	}
+	return os.WriteFile(name, generated, 0o666)
+}
@@ -0,0 +1,159 @@
+package cli_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/simplicity-load/apispec/pkg/cli"
+	"github.com/simplicity-load/apispec/pkg/http"
+)
+
+type getRequest struct {
+	ID string `json:"-" as:"id,path"`
+}
+
+type response struct {
+	Name string `json:"name"`
+}
+
+func get(ctx context.Context, r *getRequest) (*response, error)  { return nil, nil }
+func list(ctx context.Context, r *response) (*response, error)   { return nil, nil }
+func create(ctx context.Context, r *response) (*response, error) { return nil, nil }
+
+func testRoutes() *http.Path {
+	api := http.NewAPI()
+	api.Get(list, "")
+	users := api.Static("users")
+	users.Post(create, "Create a user")
+	users.Param("id").Get(get, "Get a user")
+	return api
+}
+
+func TestRoutes(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if err := cli.Run(testRoutes(), []string{"routes"}, &stdout, &stderr); err != nil {
+		t.Fatalf("routes failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	want := [][]string{
+		{"METHOD", "PATH", "HANDLER"},
+		{"GET", "/", "cli_test.list"},
+		{"POST", "/users", "cli_test.create"},
+		{"GET", "/users/{id}", "cli_test.get"},
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("got %d lines, wanted %d:\n%s", len(lines), len(want), stdout.String())
+	}
+	for i, fields := range want {
+		got := strings.Fields(lines[i])
+		if got[0] != fields[0] || got[1] != fields[1] || !strings.HasSuffix(got[2], fields[2]) {
+			t.Errorf("line %d: got: %v, wanted: %v", i, got, fields)
+		}
+	}
+}
+
+func TestLint(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	err := cli.Run(testRoutes(), []string{"lint"}, &stdout, &stderr)
+	if !errors.Is(err, cli.ErrLint) {
+		t.Fatalf("expected lint to fail, got: %v", err)
+	}
+	output := stdout.String()
+	for _, want := range []string{
+		"GET / ",
+		"missing description",
+		"GET request has JSON body fields",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("lint output is missing %q:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "/users") {
+		t.Errorf("lint reported a correct endpoint:\n%s", output)
+	}
+}
+
+func TestUnknownCommand(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if err := cli.Run(testRoutes(), []string{"nope"}, &stdout, &stderr); err == nil {
+		t.Fatal("expected unknown command to fail")
+	}
+	if !strings.Contains(stderr.String(), "generate") {
+		t.Errorf("usage missing from stderr: %s", stderr.String())
+	}
+}
+
+func TestOpenAPI(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	err := cli.Run(testRoutes(), []string{"openapi", "-title", "Users"}, &stdout, &stderr)
+	if err != nil {
+		t.Fatalf("openapi failed: %v", err)
+	}
+	if !strings.Contains(stdout.String(), `"title": "Users"`) {
+		t.Errorf("openapi output is missing the title:\n%s", stdout.String())
+	}
+}
+
+func TestOpenAPIYAML(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	err := cli.Run(testRoutes(), []string{"openapi", "-format", "yaml", "-title", "Users"}, &stdout, &stderr)
+	if err != nil {
+		t.Fatalf("openapi failed: %v", err)
+	}
+	if !strings.HasPrefix(stdout.String(), "openapi: \"3.1.0\"\n") || !strings.Contains(stdout.String(), "  title: Users\n") {
+		t.Errorf("openapi output isn't YAML:\n%s", stdout.String())
+	}
+
+	err = cli.Run(testRoutes(), []string{"openapi", "-format", "toml"}, &stdout, &stderr)
+	if err == nil {
+		t.Error("expected an unknown format to fail")
+	}
+}
+
+func TestBootstrap(t *testing.T) {
+	var buf bytes.Buffer
+	if err := cli.Bootstrap(&buf, "example.com/api", "Routes"); err != nil {
+		t.Fatalf("Bootstrap failed: %v", err)
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), "", buf.String(), 0); err != nil {
+		t.Fatalf("bootstrap program is not valid Go: %v", err)
+	}
+	if !strings.Contains(buf.String(), "cli.Main(routes.Routes())") {
+		t.Errorf("bootstrap program doesn't call Routes:\n%s", buf.String())
+	}
+	if err := cli.Bootstrap(&buf, "example.com/api", "routes"); err == nil {
+		t.Error("expected unexported routes function to fail")
+	}
+}
+
+func TestFailedGenerationKeepsOutput(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "server.go")
+	if err := os.WriteFile(output, []byte("package previous\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var stdout, stderr bytes.Buffer
+	err := cli.Run(testRoutes(), []string{"generate", "-o", output, "-server", "missing.tmpl"}, &stdout, &stderr)
+	if err == nil {
+		t.Fatal("expected a missing template to fail")
+	}
+	got, err := os.ReadFile(output)
+	if err != nil || string(got) != "package previous\n" {
+		t.Errorf("failed generation must leave the previous output, got: %q, %v", got, err)
+	}
+
+	err = cli.Run(testRoutes(), []string{"generate", "-o", output}, &stdout, &stderr)
+	if err != nil {
+		t.Fatalf("generate failed: %v", err)
+	}
+	got, err = os.ReadFile(output)
+	if err != nil || !bytes.Contains(got, []byte("func RegisterHandlers(")) {
+		t.Errorf("generate must replace the output, got: %q, %v", got, err)
+	}
+}
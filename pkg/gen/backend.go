@@ -0,0 +1,338 @@
+package generate
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"slices"
+	"sync"
+
+	e "github.com/simplicity-load/apispec/pkg/errors"
+	repr "github.com/simplicity-load/apispec/pkg/repr/http"
+)
+
+// Backend describes how a server framework is rendered from a
+// [repr.Representation].
+type Backend struct {
+	// Name is the backend's key in the registry, see [Lookup].
+	Name string
+	// Template must define a "setup" template, it is executed with the
+	// registration data:
+	//
+	//   - .Imports, .Recievers and .Endpoints
+	//   - .ValidateImport and .SetupImports
+	//   - .Authorized, set when any endpoint declares Authz
+	//   - .Options, the OPTIONS endpoints to answer with the .Allow-ed
+	//     methods of their .Path
+	//   - .CustomMethods, the methods declared besides the standard ones
+	//   - .Sentinels, the .Message and .Status of the mapped sentinel errors
+	//
+	// It may use the templates of the shared helpers, e.g. "param_helpers",
+	// "cookie_helpers" whose cookieValue reads what r.Cookie returns,
+	// "error_status" which sets status from the err a handler returned,
+	// "error_status_check" which panics unless Options.ErrorStatus maps the
+	// .Sentinels, or "problem_helpers" building the RFC 7807 documents errors
+	// are answered with, sent by a writeProblem the template defines.
+	// Templates using "options" must define "hook_params", the parameters the
+	// hooks of Options take before the error or response (e.g.
+	// "c *fiber.Ctx"), and "hook_result", " error" when the hooks return one.
+	//
+	// The following functions are available and form a stable contract for
+	// custom templates:
+	//
+	//   - importIdent(import string) string: identifier an import path is
+	//     bound to
+	//   - isPointer(bool) string: "*" for pointer recievers, "" otherwise
+	//   - formatMiddleware(repr.Middlewares) []string: middleware call
+	//     expressions
+	//   - httpMethodToFnIdent(http.Method) string: "GET" as "Get"
+	//   - pathToString(repr.PathStrings) (string, error): the route in the
+	//     backend's syntax, see PathToString
+	//   - toRequestParams(*repr.Data) []*param: non JSON request fields, with
+	//     the accessor from RequestParams as .FunctionName, the expression
+	//     reading the field as .Value and where the field is read from, e.g.
+	//     "query", as .In
+	//   - toRespParams(*repr.Data) []*param: non JSON response fields, with
+	//     the setter from ResponseParams as .FunctionName
+	Template string
+	// SetupImports lists the imports of the generated file, the validator is
+	// imported from validateUrl as validate by the template.
+	SetupImports func(validateUrl string) []string
+	// PathToString renders a route in the framework's syntax.
+	PathToString func(repr.PathStrings) (string, error)
+	// RequestParams maps where a request field is read from to the accessor
+	// taking its name, e.g. "r.Header.Get".
+	RequestParams map[repr.SerializationType]string
+	// ResponseParams maps where a response field is written to the setter
+	// taking its name and value, e.g. "w.Header().Set". Cookie setters are
+	// called with the framework's own arguments, e.g. a *http.Cookie.
+	ResponseParams map[repr.SerializationType]string
+	// Wildcard renders the expression reading the wildcard segment bound to
+	// name, when nil it is read like any other path parameter.
+	Wildcard func(name string) string
+}
+
+//go:embed gofiber/fiber.tmpl
+var fiberTempl string
+
+//go:embed nethttp/nethttp.tmpl
+var netHTTPTempl string
+
+//go:embed chi/chi.tmpl
+var chiTempl string
+
+//go:embed echo/echo.tmpl
+var echoTempl string
+
+//go:embed gin/gin.tmpl
+var ginTempl string
+
+// paramImports are the imports used by the "param_helpers" template.
+var paramImports = []string{
+	"encoding",
+	"encoding/json",
+	"fmt",
+	"reflect",
+	"strconv",
+	"time",
+}
+
+// problemImports are the imports used by the "problem_helpers" template
+// beyond [paramImports].
+var problemImports = []string{
+	"strings",
+}
+
+// FiberBackend requires the custom methods routes are declared with to be
+// listed in fiber.Config.RequestMethods, RegisterHandlers panics otherwise.
+var FiberBackend = Backend{
+	Name:     "fiber",
+	Template: fiberTempl,
+	SetupImports: func(string) []string {
+		return slices.Concat([]string{
+			"context",
+			"errors",
+			"net/http",
+			"slices",
+			"github.com/gofiber/fiber/v2",
+		}, paramImports, problemImports)
+	},
+	PathToString: repr.PathToURL,
+	RequestParams: map[repr.SerializationType]string{
+		repr.SerializationPATH:   "c.Params",
+		repr.SerializationQUERY:  "c.Query",
+		repr.SerializationHEADER: "c.Get",
+		repr.SerializationCOOKIE: "c.Cookies",
+	},
+	ResponseParams: map[repr.SerializationType]string{
+		repr.SerializationHEADER: "c.Set",
+		repr.SerializationCOOKIE: "c.Cookie",
+	},
+	Wildcard: func(string) string { return `c.Params("*")` },
+}
+
+var NetHTTPBackend = Backend{
+	Name:     "nethttp",
+	Template: netHTTPTempl,
+	SetupImports: func(string) []string {
+		return slices.Concat([]string{
+			"context",
+			"errors",
+			"io",
+			"net/http",
+		}, paramImports, problemImports)
+	},
+	PathToString: pathToServeMux,
+	RequestParams: map[repr.SerializationType]string{
+		repr.SerializationPATH:   "r.PathValue",
+		repr.SerializationQUERY:  "r.URL.Query().Get",
+		repr.SerializationHEADER: "r.Header.Get",
+		repr.SerializationCOOKIE: "r.Cookie",
+	},
+	ResponseParams: map[repr.SerializationType]string{
+		repr.SerializationHEADER: "w.Header().Set",
+		repr.SerializationCOOKIE: "http.SetCookie",
+	},
+}
+
+// ChiBackend relies on chi populating [net/http.Request.PathValue], which
+// it does since v5.0.12.
+var ChiBackend = Backend{
+	Name:     "chi",
+	Template: chiTempl,
+	SetupImports: func(string) []string {
+		return slices.Concat([]string{
+			"context",
+			"errors",
+			"io",
+			"net/http",
+			"github.com/go-chi/chi/v5",
+		}, paramImports, problemImports)
+	},
+	PathToString: pathToRoot(wildcardAs(repr.PathToPattern, func(string) string { return "*" })),
+	RequestParams: map[repr.SerializationType]string{
+		repr.SerializationPATH:   "r.PathValue",
+		repr.SerializationQUERY:  "r.URL.Query().Get",
+		repr.SerializationHEADER: "r.Header.Get",
+		repr.SerializationCOOKIE: "r.Cookie",
+	},
+	ResponseParams: map[repr.SerializationType]string{
+		repr.SerializationHEADER: "w.Header().Set",
+		repr.SerializationCOOKIE: "http.SetCookie",
+	},
+	Wildcard: func(string) string { return `r.PathValue("*")` },
+}
+
+var EchoBackend = Backend{
+	Name:     "echo",
+	Template: echoTempl,
+	SetupImports: func(string) []string {
+		return slices.Concat([]string{
+			"context",
+			"errors",
+			"io",
+			"net/http",
+			"github.com/labstack/echo/v4",
+		}, paramImports, problemImports)
+	},
+	PathToString: pathToRoot(repr.PathToURL),
+	RequestParams: map[repr.SerializationType]string{
+		repr.SerializationPATH:   "c.Param",
+		repr.SerializationQUERY:  "c.QueryParam",
+		repr.SerializationHEADER: "c.Request().Header.Get",
+		repr.SerializationCOOKIE: "c.Cookie",
+	},
+	ResponseParams: map[repr.SerializationType]string{
+		repr.SerializationHEADER: "c.Response().Header().Set",
+		repr.SerializationCOOKIE: "c.SetCookie",
+	},
+	Wildcard: func(string) string { return `c.Param("*")` },
+}
+
+// GinBackend names its catch-all segments, gin keeps the leading slash of
+// the value they match.
+var GinBackend = Backend{
+	Name:     "gin",
+	Template: ginTempl,
+	SetupImports: func(string) []string {
+		return slices.Concat([]string{
+			"context",
+			"errors",
+			"io",
+			"net/http",
+			"github.com/gin-gonic/gin",
+		}, paramImports, problemImports)
+	},
+	PathToString: pathToRoot(wildcardAs(repr.PathToURL, func(name string) string { return "*" + name })),
+	RequestParams: map[repr.SerializationType]string{
+		repr.SerializationPATH:   "c.Param",
+		repr.SerializationQUERY:  "c.Query",
+		repr.SerializationHEADER: "c.GetHeader",
+		repr.SerializationCOOKIE: "c.Cookie",
+	},
+	ResponseParams: map[repr.SerializationType]string{
+		repr.SerializationHEADER: "c.Header",
+		repr.SerializationCOOKIE: "c.SetCookie",
+	},
+	Wildcard: func(name string) string {
+		return fmt.Sprintf(`strings.TrimPrefix(c.Param(%q), "/")`, name)
+	},
+}
+
+// DefaultBackend is used when no backend is selected.
+const DefaultBackend = "fiber"
+
+var (
+	ErrNoSetupTemplate  = errors.New(`template must define "setup"`)
+	ErrNoClientTemplate = errors.New(`template must define "client"`)
+)
+
+var backends = struct {
+	sync.RWMutex
+	m map[string]Backend
+}{m: make(map[string]Backend)}
+
+func init() {
+	for _, b := range []Backend{
+		FiberBackend,
+		NetHTTPBackend,
+		ChiBackend,
+		EchoBackend,
+		GinBackend,
+	} {
+		if err := Register(b); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// Register makes b selectable by its name, names must be unique.
+func Register(b Backend) error {
+	if b.Name == "" {
+		return e.ErrFailedAction("register backend", e.ErrNoValue)
+	}
+	backends.Lock()
+	defer backends.Unlock()
+	if _, ok := backends.m[b.Name]; ok {
+		return e.ErrFailedActionWithItem("register backend", b.Name, ErrBackendExists)
+	}
+	backends.m[b.Name] = b
+	return nil
+}
+
+var ErrBackendExists = errors.New("backend already registered")
+
+// Lookup returns the backend registered under name.
+func Lookup(name string) (Backend, error) {
+	backends.RLock()
+	defer backends.RUnlock()
+	b, ok := backends.m[name]
+	if !ok {
+		return Backend{}, e.ErrBadValueFromList("backend", name, backendNames())
+	}
+	return b, nil
+}
+
+// Backends lists the names of the registered backends in sorted order.
+func Backends() []string {
+	backends.RLock()
+	defer backends.RUnlock()
+	return backendNames()
+}
+
+func backendNames() []string {
+	names := make([]string, 0, len(backends.m))
+	for name := range backends.m {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// FromFS returns a copy of base that renders the template stored at name in
+// fsys instead of its own.
+func FromFS(base Backend, fsys fs.FS, name string) (Backend, error) {
+	templ, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return Backend{}, e.ErrFailedActionWithItem("read template", name, err)
+	}
+	base.Name = name
+	base.Template = string(templ)
+	return base, nil
+}
+
+// pathToRoot renders the root path as "/" for routers that reject empty
+// routes.
+func pathToRoot(fn func(repr.PathStrings) (string, error)) func(repr.PathStrings) (string, error) {
+	return func(paths repr.PathStrings) (string, error) {
+		url, err := fn(paths)
+		if err != nil {
+			return "", err
+		}
+		if url == "" {
+			return "/", nil
+		}
+		return url, nil
+	}
+}
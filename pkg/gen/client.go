@@ -0,0 +1,316 @@
+package generate
+
+import (
+	_ "embed"
+	"fmt"
+	"io"
+	"io/fs"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+
+	e "github.com/simplicity-load/apispec/pkg/errors"
+	"github.com/simplicity-load/apispec/pkg/http"
+	repr "github.com/simplicity-load/apispec/pkg/repr/http"
+)
+
+// Client describes a client rendered from a [repr.Representation].
+//
+// Template must define a "client" template, it is executed with the client
+// data (.Package, .Imports and .Endpoints, where every endpoint carries a
+// unique .MethodName) and may use the shared helpers. On top of importIdent
+// and pathToURL the following functions are available:
+//
+//   - clientPath(*repr.Endpoint) (string, error): a Go expression building
+//     the URL path from the path bound fields of req
+//   - paramsOf(*repr.Data, serialization string) []*param: the fields of
+//     the given serialization type, e.g. "QUERY", with the field as .Field
+//   - tsType(*repr.StructField) string: the TypeScript type of a field,
+//     the types listed in .Types are referred to by their .TypeName
+//   - tsFields([]*repr.StructField) []*repr.StructField: the fields of a
+//     TypeScript type, without the json:"-" ones
+//   - tsOptional(*repr.StructField) string: "?" for pointer fields, which
+//     may be left out
+//   - tsParse(field *repr.StructField, value string) string: the conversion
+//     of the string value to the TypeScript type of field
+//   - tsProp(string) string, tsAccess(ident, prop string) string: a
+//     TypeScript property key and its access on ident
+//   - tsPath(*repr.Endpoint) (string, error): like clientPath, as a
+//     TypeScript template literal over req
+//   - paramNames(*repr.Data) []string: quoted names of the non JSON fields
+//   - fieldName(*repr.StructField) string: the serialized name of a field
+//   - lowerFirst(string) string
+//   - tsFuncName(string) string: lowerFirst, with a "_" suffix for reserved
+//     words
+//
+// The data also lists every request, response and recursive type once as
+// .Types, each with a unique .TypeName, endpoints refer to them by .BodyType
+// and .ResponseType.
+type Client struct {
+	Name     string
+	Template string
+}
+
+//go:embed goclient/client.tmpl
+var goClientTempl string
+
+//go:embed typescript/client.ts.tmpl
+var typeScriptClientTempl string
+
+var GoClient = Client{
+	Name:     "go",
+	Template: goClientTempl,
+}
+
+var TypeScriptClient = Client{
+	Name:     "typescript",
+	Template: typeScriptClientTempl,
+}
+
+// DefaultClient is used when no client is selected.
+const DefaultClient = "go"
+
+var clients = struct {
+	sync.RWMutex
+	m map[string]Client
+}{m: make(map[string]Client)}
+
+func init() {
+	for _, c := range []Client{
+		GoClient,
+		TypeScriptClient,
+	} {
+		if err := RegisterClient(c); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// RegisterClient makes c selectable by its name, names must be unique.
+func RegisterClient(c Client) error {
+	if c.Name == "" {
+		return e.ErrFailedAction("register client", e.ErrNoValue)
+	}
+	clients.Lock()
+	defer clients.Unlock()
+	if _, ok := clients.m[c.Name]; ok {
+		return e.ErrFailedActionWithItem("register client", c.Name, ErrBackendExists)
+	}
+	clients.m[c.Name] = c
+	return nil
+}
+
+// LookupClient returns the client registered under name.
+func LookupClient(name string) (Client, error) {
+	clients.RLock()
+	defer clients.RUnlock()
+	c, ok := clients.m[name]
+	if !ok {
+		names := make([]string, 0, len(clients.m))
+		for name := range clients.m {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		return Client{}, e.ErrBadValueFromList("client", name, names)
+	}
+	return c, nil
+}
+
+// ClientFromFS returns a client rendering the template stored at name in
+// fsys.
+func ClientFromFS(fsys fs.FS, name string) (Client, error) {
+	templ, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return Client{}, e.ErrFailedActionWithItem("read template", name, err)
+	}
+	return Client{Name: name, Template: string(templ)}, nil
+}
+
+type clientEndpointTemplateData struct {
+	*repr.Endpoint
+	IsGet         bool
+	MethodName    string
+	BodyIdent     string
+	ResponseIdent string
+	BodyType      string
+	ResponseType  string
+}
+
+// GenerateClient renders the client described by c for representation into
+// output as package pkg.
+func GenerateClient(
+	c Client,
+	representation repr.Representation,
+	output io.Writer,
+	pkg string,
+) error {
+	imports := newImportSet()
+	endpoints := make(repr.Endpoints, 0)
+	for endpoint := range representation.Routes.AllEndpoints() {
+		// clients request the GET endpoint rather than its HEAD
+		if endpoint.AutoHead {
+			continue
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+	for imp := range endpoints.DataImports() {
+		imports.add(imp)
+	}
+	impSortSet, impSort := imports.sort()
+
+	types, typeNames := clientTypes(endpoints)
+	t, err := template.New("").Funcs(template.FuncMap{
+		"importIdent": func(imp string) string {
+			return impSortSet.get(imp)
+		},
+		"clientPath": clientPath,
+		"pathToURL":  pathToRoot(repr.PathToPattern),
+		"paramsOf": func(data *repr.Data, t string) []*param {
+			return paramsOf(data, repr.SerializationType(t))
+		},
+		"tsType":     tsTyper(typeNames),
+		"tsOptional": tsOptional,
+		"tsFields":   tsFields,
+		"tsParse":    tsParse,
+		"tsProp":     tsProp,
+		"tsAccess":   tsAccess,
+		"tsPath":     tsPath,
+		"paramNames": paramNames,
+		"fieldName":  fieldName,
+		"lowerFirst": lowerFirst,
+		"tsFuncName": tsFuncName,
+	}).Parse(helpersTempl)
+	if err != nil {
+		return err
+	}
+	t, err = t.Parse(c.Template)
+	if err != nil {
+		return e.ErrFailedActionWithItem("parse template", c.Name, err)
+	}
+	if t.Lookup("client") == nil {
+		return e.ErrFailedActionWithItem("lookup template", c.Name, ErrNoClientTemplate)
+	}
+
+	handlerNames := make(map[string]bool, len(endpoints))
+	for _, endpoint := range endpoints {
+		handlerNames[endpoint.Handler.Name] = true
+	}
+	names := make(map[string]bool, len(endpoints))
+	data := make([]clientEndpointTemplateData, len(endpoints))
+	for i, endpoint := range endpoints {
+		data[i] = clientEndpointTemplateData{
+			Endpoint:      endpoint,
+			IsGet:         endpoint.Method == http.GET || endpoint.Method == http.HEAD,
+			MethodName:    uniqueName(names, handlerNames, endpoint.Handler.Name),
+			BodyIdent:     impSortSet.get(endpoint.Body.Import),
+			ResponseIdent: impSortSet.get(endpoint.Response.Import),
+			BodyType: typeNames[repr.TypeKey{
+				Import: endpoint.Body.Import,
+				Name:   endpoint.Body.Name,
+			}],
+			ResponseType: typeNames[repr.TypeKey{
+				Import: endpoint.Response.Import,
+				Name:   endpoint.Response.Name,
+			}],
+		}
+	}
+
+	gen, err := templateToString(t.Lookup("client"), struct {
+		Package   string
+		Imports   []importer
+		Types     []clientType
+		Endpoints []clientEndpointTemplateData
+	}{
+		Package:   pkg,
+		Imports:   impSort,
+		Types:     types,
+		Endpoints: data,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(output, gen)
+	return err
+}
+
+// uniqueName numbers repeated names in order of appearance, the first
+// occurrence keeps its name and the numbered ones skip the taken and
+// reserved names.
+func uniqueName(taken, reserved map[string]bool, name string) string {
+	candidate := name
+	for i := 2; taken[candidate] || candidate != name && reserved[candidate]; i++ {
+		candidate = name + strconv.Itoa(i)
+	}
+	taken[candidate] = true
+	return candidate
+}
+
+// clientPath renders the path of endpoint as a Go expression, substituting
+// parameters with the path bound field of req they name.
+func clientPath(endpoint *repr.Endpoint) (string, error) {
+	parts := make([]string, 0)
+	static := ""
+	for path := range repr.PathStrings(endpoint.Path).NoRootPaths() {
+		static += "/"
+		switch path.Type {
+		case repr.PathSTATIC:
+			static += path.Name
+		case repr.PathPARAM:
+			field := pathField(endpoint.Body, path.Name)
+			if field == nil {
+				return "", e.ErrFailedActionWithItem("find path field", path.Name, e.ErrNoValue)
+			}
+			parts = append(parts,
+				strconv.Quote(static),
+				fmt.Sprintf("url.PathEscape(formatParam(req.%s))", field.Name))
+			static = ""
+		case repr.PathWILDCARD:
+			field := pathField(endpoint.Body, path.Name)
+			if field == nil {
+				return "", e.ErrFailedActionWithItem("find path field", path.Name, e.ErrNoValue)
+			}
+			// the segments of a wildcard are escaped one by one
+			parts = append(parts,
+				strconv.Quote(static),
+				fmt.Sprintf(`strings.ReplaceAll(url.PathEscape(req.%s), "%%2F", "/")`, field.Name))
+			static = ""
+		default:
+			return "", e.ErrBadValueFromList("path type", path.Type, repr.ValidPathTypes)
+		}
+	}
+	if static != "" || len(parts) == 0 {
+		if static == "" {
+			static = "/"
+		}
+		parts = append(parts, strconv.Quote(static))
+	}
+	return strings.Join(parts, " + "), nil
+}
+
+func pathField(data *repr.Data, name string) *repr.StructField {
+	for _, field := range data.Fields {
+		if field.Serialization != nil &&
+			field.Serialization.Type == repr.SerializationPATH &&
+			field.Serialization.Name == name {
+			return field
+		}
+	}
+	return nil
+}
+
+func paramsOf(data *repr.Data, t repr.SerializationType) []*param {
+	params := make([]*param, 0)
+	for _, field := range data.Fields {
+		if field.Serialization == nil || field.Serialization.Type != t {
+			continue
+		}
+		params = append(params, &param{
+			Name:          field.Name,
+			Serialization: field.Serialization.Name,
+			Field:         field,
+		})
+	}
+	return params
+}
@@ -2,51 +2,87 @@ package generate
 
 import (
 	"bytes"
+	"cmp"
 	_ "embed"
 	"fmt"
 	"io"
+	"slices"
 	"strings"
 	"sync"
 	"text/template"
 
+	e "github.com/simplicity-load/apispec/pkg/errors"
 	"github.com/simplicity-load/apispec/pkg/http"
 	repr "github.com/simplicity-load/apispec/pkg/repr/http"
 )
 
-//go:embed gofiber/fiber.tmpl
-var templ string
-
-func getRegisterTemplate(imports importSet[sorted], recievers recieverSet[sorted]) *template.Template {
-	return sync.OnceValue(func() *template.Template {
-		t, err := template.New("").Funcs(template.FuncMap{
-			"importIdent": func(imp string) string {
-				return imports.get(imp)
-			},
-			"isPointer": func(isPointer bool) string {
-				if isPointer {
-					return "*"
-				}
-				return ""
-			},
-			"formatMiddleware": func(middleware repr.Middlewares) []string {
-				return formatMiddleware(middleware, imports, recievers)
-			},
-			"httpMethodToFnIdent": httpMethodToFiber,
-			"pathToString":        repr.PathToURL,
-			"toRequestParams":     toRequestParams,
-			"toRespParams":        toRespParams,
-		}).Parse(templ)
-		if err != nil {
-			panic(err)
-		}
-		return t
-	})()
+//go:embed common/helpers.tmpl
+var helpersTempl string
+
+func getRegisterTemplate(b Backend, imports importSet[sorted], recievers recieverSet[sorted]) (*template.Template, error) {
+	t, err := template.New("").Funcs(funcMap(b, imports, recievers)).Parse(helpersTempl)
+	if err != nil {
+		return nil, err
+	}
+	t, err = t.Parse(b.Template)
+	if err != nil {
+		return nil, e.ErrFailedActionWithItem("parse template", b.Name, err)
+	}
+	if t.Lookup("setup") == nil {
+		return nil, e.ErrFailedActionWithItem("lookup template", b.Name, ErrNoSetupTemplate)
+	}
+	return t, nil
 }
 
+// funcMap builds the functions available to every backend template, see
+// [Backend] for their contract.
+func funcMap(b Backend, imports importSet[sorted], recievers recieverSet[sorted]) template.FuncMap {
+	return template.FuncMap{
+		"importIdent": func(imp string) string {
+			return imports.get(imp)
+		},
+		"isPointer": func(isPointer bool) string {
+			if isPointer {
+				return "*"
+			}
+			return ""
+		},
+		"formatMiddleware": func(middleware repr.Middlewares) []string {
+			return formatMiddleware(middleware, imports, recievers)
+		},
+		"httpMethodToFnIdent": httpMethodToFiber,
+		"pathToString":        b.PathToString,
+		"toRequestParams":     toParams(b.RequestParams, b.Wildcard),
+		"toRespParams":        toParams(b.ResponseParams, nil),
+	}
+}
+
+// Generate renders a Fiber server for representation into output.
 func Generate(
 	representation repr.Representation,
 	output io.Writer,
 	validateUrl string,
+) error {
+	return GenerateWith(FiberBackend, representation, output, validateUrl)
+}
+
+// GenerateNetHTTP renders a standard library server for representation into
+// output, registering routes on a [net/http.ServeMux] with Go 1.22 patterns.
+func GenerateNetHTTP(
+	representation repr.Representation,
+	output io.Writer,
+	validateUrl string,
+) error {
+	return GenerateWith(NetHTTPBackend, representation, output, validateUrl)
+}
+
+// GenerateWith renders the server described by b for representation into
+// output.
+func GenerateWith(
+	b Backend,
+	representation repr.Representation,
+	output io.Writer,
+	validateUrl string,
 ) error {
 	imports := newImportSet()
 	recievers := newRecieverSet()
@@ -55,11 +91,18 @@ func Generate(
 	impSortSet, impSort := imports.sort()
 	recvSortSet, recvSort := recievers.sort()
 
-	t := getRegisterTemplate(impSortSet, recvSortSet)
+	t, err := getRegisterTemplate(b, impSortSet, recvSortSet)
+	if err != nil {
+		return err
+	}
 
 	enrinchedEndpoints := make([]endpointTemplateData, len(endpoints))
 	for i, e := range endpoints {
-		recvIdent := recvSortSet.get(e.Handler.Reciever, e.Handler.Import)
+		// plain functions are called through their package identifier
+		recvIdent := impSortSet.get(e.Handler.Import)
+		if e.Handler.Reciever != nil {
+			recvIdent = recvSortSet.get(e.Handler.Reciever, e.Handler.Import)
+		}
 		impIdent := impSortSet.get(e.Body.Import)
 		enrinchedEndpoints[i] = endpointTemplateData{
 			AppIdent:      "app",
@@ -77,15 +120,22 @@ func Generate(
 		Endpoints      []endpointTemplateData
 		ValidateImport string
 		SetupImports   []string
+		Authorized     bool
+		Options        []optionsTemplateData
+		CustomMethods  []http.Method
+		Sentinels      []sentinelTemplateData
 	}{
 		Recievers:      recvSort,
 		Imports:        impSort,
 		Endpoints:      enrinchedEndpoints,
 		ValidateImport: validateUrl,
-		SetupImports: []string{
-			"github.com/gofiber/fiber/v2",
-			validateUrl,
-		},
+		SetupImports:   b.SetupImports(validateUrl),
+		Authorized: slices.ContainsFunc(endpoints, func(e endpointTemplateData) bool {
+			return len(e.Authorization) > 0
+		}),
+		Options:       generateOptions(representation.Routes),
+		CustomMethods: customMethods(endpoints),
+		Sentinels:     sentinels(representation.ErrorStatusMap),
 	}
 	gen, err := templateToString(t.Lookup("setup"), data)
 	if err != nil {
@@ -124,8 +174,9 @@ func generateEndpointsIter(
 	endpointAcc := make([]endpointTemplateData, len(path.Endpoints))
 	for i, e := range path.Endpoints {
 		endpointAcc[i] = endpointTemplateData{
-			Endpoint:   e,
-			IsGet:      e.Method == http.GET,
+			Endpoint: e,
+			// HEAD requests have no body either
+			IsGet:      e.Method == http.GET || e.Method == http.HEAD,
 			Middleware: path.Middleware,
 		}
 	}
@@ -137,6 +188,66 @@ func generateEndpointsIter(
 	return endpointAcc
 }
 
+// optionsTemplateData is an OPTIONS endpoint answered with the methods of a
+// path that doesn't declare one.
+type optionsTemplateData struct {
+	Method http.Method
+	Path   []*repr.PathString
+	// Allow lists the methods of the path, e.g. "GET, OPTIONS".
+	Allow string
+}
+
+func generateOptions(path *repr.Path) []optionsTemplateData {
+	options := make([]optionsTemplateData, 0)
+	methods := make([]string, 0, len(path.Endpoints)+1)
+	for _, e := range path.Endpoints {
+		methods = append(methods, string(e.Method))
+	}
+	if len(methods) > 0 && !slices.Contains(methods, string(http.OPTIONS)) {
+		methods = append(methods, string(http.OPTIONS))
+		slices.Sort(methods)
+		options = append(options, optionsTemplateData{
+			Method: http.OPTIONS,
+			Path:   path.Endpoints[0].Path,
+			Allow:  strings.Join(methods, ", "),
+		})
+	}
+	for _, subPath := range path.SubPath {
+		options = append(options, generateOptions(subPath)...)
+	}
+	return options
+}
+
+// customMethods lists the methods outside of [http.Methods] endpoints are
+// declared with, once each.
+func customMethods(endpoints []endpointTemplateData) []http.Method {
+	methods := make([]http.Method, 0)
+	for _, e := range endpoints {
+		if !slices.Contains(http.Methods, e.Method) && !slices.Contains(methods, e.Method) {
+			methods = append(methods, e.Method)
+		}
+	}
+	return methods
+}
+
+type sentinelTemplateData struct {
+	Message string
+	Status  int
+}
+
+// sentinels lists the sentinel errors of statuses, each once even when
+// another shares its message.
+func sentinels(statuses map[error]int) []sentinelTemplateData {
+	sentinels := make([]sentinelTemplateData, 0, len(statuses))
+	for err, status := range statuses {
+		sentinels = append(sentinels, sentinelTemplateData{Message: err.Error(), Status: status})
+	}
+	slices.SortFunc(sentinels, func(a, b sentinelTemplateData) int {
+		return cmp.Or(strings.Compare(a.Message, b.Message), cmp.Compare(a.Status, b.Status))
+	})
+	return sentinels
+}
+
 type endpointTemplateData struct {
 	*repr.Endpoint
 	IsGet         bool
@@ -171,50 +282,62 @@ func formatMiddleware(
 	return formatted
 }
 
-func toRequestParams(data *repr.Data) []*param {
-	return toParams(serializationToRequestFiber)(data)
-}
-func serializationToRequestFiber(t repr.SerializationType) (string, bool) {
-	fnName, ok := map[repr.SerializationType]string{
-		repr.SerializationPATH:   "c.Params",
-		repr.SerializationQUERY:  "c.Query",
-		repr.SerializationHEADER: "c.Get",
-	}[t]
-	return fnName, ok
+// pathToServeMux renders paths as a ServeMux pattern, anchoring the root so
+// it doesn't act as a catch-all.
+func pathToServeMux(paths repr.PathStrings) (string, error) {
+	url, err := repr.PathToPattern(paths)
+	if err != nil {
+		return "", err
+	}
+	if url == "" {
+		return "/{$}", nil
+	}
+	return url, nil
 }
 
-func toRespParams(data *repr.Data) []*param {
-	return toParams(serializationToRespFiber)(data)
-}
-func serializationToRespFiber(t repr.SerializationType) (string, bool) {
-	fnName, ok := map[repr.SerializationType]string{
-		repr.SerializationHEADER: "c.Set",
-		repr.SerializationCOOKIE: "c.Set",
-	}[t]
-	return fnName, ok
+// wildcardAs renders the wildcard ending paths with as, for routers whose
+// catch-all syntax differs from the one fn renders.
+func wildcardAs(
+	fn func(repr.PathStrings) (string, error),
+	as func(name string) string,
+) func(repr.PathStrings) (string, error) {
+	return func(paths repr.PathStrings) (string, error) {
+		url, err := fn(paths)
+		if err != nil || len(paths) == 0 {
+			return url, err
+		}
+		last := paths[len(paths)-1]
+		if last.Type != repr.PathWILDCARD {
+			return url, nil
+		}
+		return url[:strings.LastIndex(url, "/")+1] + as(last.Name), nil
+	}
 }
 
 func toParams(
-	fn func(repr.SerializationType) (string, bool),
+	fnNames map[repr.SerializationType]string,
+	wildcard func(name string) string,
 ) func(data *repr.Data) []*param {
 	return func(data *repr.Data) []*param {
 		params := make([]*param, 0, len(data.Fields))
 		for _, fields := range data.Fields {
-			fnName, ok := fn(fields.Serialization.Type)
+			fnName, ok := fnNames[fields.Serialization.Type]
 			if !ok {
 				continue
 			}
 
 			serializationName := fields.Serialization.Name
-			if fields.Serialization.Type ==
-				repr.SerializationCOOKIE {
-				serializationName = "set-cookie"
+			value := fmt.Sprintf("%s(%q)", fnName, serializationName)
+			if fields.Serialization.Wildcard && wildcard != nil {
+				value = wildcard(serializationName)
 			}
-
 			params = append(params, &param{
 				Name:          fields.Name,
 				Serialization: serializationName,
+				In:            fields.Serialization.In(),
 				FunctionName:  fnName,
+				Value:         value,
+				Field:         fields,
 			})
 		}
 		return params
@@ -224,7 +347,13 @@ func toParams(
 type param struct {
 	Name          string
 	Serialization string
-	FunctionName  string
+	// In is where the param is read from or written to, e.g. "query".
+	In           string
+	FunctionName string
+	// Value is the expression reading a request param.
+	Value string
+	// Field is the field bound to the param.
+	Field *repr.StructField
 }
 
 var bufferPool = sync.Pool{
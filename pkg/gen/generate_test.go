@@ -3,7 +3,13 @@ package generate_test
 import (
 	"bytes"
 	"context"
+	"errors"
+	"go/parser"
+	"go/token"
+	"strings"
 	"testing"
+	"testing/fstest"
+	"time"
 
 	"github.com/simplicity-load/apispec/pkg/gen"
 	"github.com/simplicity-load/apispec/pkg/http"
@@ -22,6 +28,16 @@ func (h testHandler) Post(ctx context.Context, param *X) (*X, error)   { return
 func (h testHandler) Put(ctx context.Context, param *X) (*X, error)    { return nil, nil }
 func (h testHandler) Patch(ctx context.Context, param *X) (*X, error)  { return nil, nil }
 func (h testHandler) Delete(ctx context.Context, param *X) (*X, error) { return nil, nil }
+
+type W struct {
+	Wus string `json:"-" as:"wus,path"`
+	Y   string `json:"y"`
+}
+
+func (h testHandler) GetW(ctx context.Context, param *W) (*X, error)    { return nil, nil }
+func (h testHandler) PatchW(ctx context.Context, param *W) (*X, error)  { return nil, nil }
+func (h testHandler) DeleteW(ctx context.Context, param *W) (*X, error) { return nil, nil }
+
 func TestGenerate(t *testing.T) {
 	h := testHandler{}
 	app := http.NewAPI()
@@ -30,14 +46,14 @@ func TestGenerate(t *testing.T) {
 	sus := app.Static("sus")
 	sus.Get(h.Get, "desc")
 	wus := sus.Param("wus")
-	wus.Get(h.Get, "desc")
+	wus.Get(h.GetW, "desc")
 	paths, err := server.ParsePaths(app)
 	if err != nil {
 		t.Fatalf("ParsePaths failed: %v", err)
 	}
 
 	var buf bytes.Buffer
-	err = generate.Generate(repr.Representation{Routes: paths}, &buf, "")
+	err = generate.Generate(repr.NewRepresentation(paths), &buf, "")
 	if err != nil {
 		t.Fatalf("Generate failed: %v", err)
 	}
@@ -47,3 +63,539 @@ func TestGenerate(t *testing.T) {
 		t.Error("Generate produced empty output")
 	}
 }
+
+func TestGenerateNetHTTP(t *testing.T) {
+	h := testHandler{}
+	app := http.NewAPI()
+	app.Get(h.Get, "desc")
+	app.Post(h.Post, "desc")
+	sus := app.Static("sus")
+	sus.Get(h.Get, "desc")
+	wus := sus.Param("wus")
+	wus.Get(h.GetW, "desc")
+	paths, err := server.ParsePaths(app)
+	if err != nil {
+		t.Fatalf("ParsePaths failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	err = generate.GenerateNetHTTP(repr.NewRepresentation(paths), &buf, "example.com/validate")
+	if err != nil {
+		t.Fatalf("GenerateNetHTTP failed: %v", err)
+	}
+
+	output := buf.String()
+	if _, err := parser.ParseFile(token.NewFileSet(), "", output, 0); err != nil {
+		t.Fatalf("generated code is not valid Go: %v", err)
+	}
+	for _, pattern := range []string{
+		`"GET /{$}"`,
+		`"POST /{$}"`,
+		`"GET /sus"`,
+		`"GET /sus/{wus}"`,
+	} {
+		if !strings.Contains(output, pattern) {
+			t.Errorf("generated code is missing pattern %s", pattern)
+		}
+	}
+}
+
+func testRoutes(t *testing.T) repr.Representation {
+	t.Helper()
+	h := testHandler{}
+	app := http.NewAPI()
+	app.MapErrors(http.ErrorIs(errNotFound, 404))
+	app.Get(h.Get, "desc")
+	app.Post(h.Post, "desc")
+	sus := app.Static("sus")
+	sus.Put(h.Put, "desc")
+	wus := sus.Param("wus")
+	wus.Patch(h.PatchW, "desc")
+	wus.Delete(h.DeleteW, "desc", http.Errors(http.ErrorAs[*conflictError](409)))
+	paths, err := server.ParsePaths(app)
+	if err != nil {
+		t.Fatalf("ParsePaths failed: %v", err)
+	}
+	return repr.NewRepresentation(paths)
+}
+
+func TestGenerateBackends(t *testing.T) {
+	representation := testRoutes(t)
+	for _, name := range generate.Backends() {
+		t.Run(name, func(t *testing.T) {
+			backend, err := generate.Lookup(name)
+			if err != nil {
+				t.Fatalf("Lookup failed: %v", err)
+			}
+			var buf bytes.Buffer
+			err = generate.GenerateWith(backend, representation, &buf, "example.com/validate")
+			if err != nil {
+				t.Fatalf("GenerateWith failed: %v", err)
+			}
+			if _, err := parser.ParseFile(token.NewFileSet(), "", buf.String(), 0); err != nil {
+				t.Fatalf("generated code is not valid Go: %v\n%s", err, buf.String())
+			}
+		})
+	}
+}
+
+var errNotFound = errors.New("not found")
+
+type conflictError struct{}
+
+func (*conflictError) Error() string { return "conflict" }
+
+func TestGenerateErrorStatus(t *testing.T) {
+	representation := testRoutes(t)
+	for _, name := range generate.Backends() {
+		b, err := generate.Lookup(name)
+		if err != nil {
+			t.Fatalf("Lookup %s failed: %v", name, err)
+		}
+		var buf bytes.Buffer
+		if err := generate.GenerateWith(b, representation, &buf, "example.com/validate"); err != nil {
+			t.Fatalf("GenerateWith %s failed: %v", name, err)
+		}
+		output := buf.String()
+		for _, want := range []string{
+			"opts Options,",
+			"case errorAs[*",
+			"status = 409",
+			"errorStatus(opts.ErrorStatus, err)",
+			"requireErrorStatus(opts.ErrorStatus, []sentinelStatus{\n\t\t{\"not found\", 404},",
+		} {
+			if !strings.Contains(output, want) {
+				t.Errorf("%s: generated code is missing %s", name, want)
+			}
+		}
+		if strings.Count(output, "case errorAs[") != 1 {
+			t.Errorf("%s: error types must only be matched on the endpoints they're mapped on", name)
+		}
+	}
+}
+
+func TestGenerateHooks(t *testing.T) {
+	representation := testRoutes(t)
+	for _, name := range generate.Backends() {
+		b, err := generate.Lookup(name)
+		if err != nil {
+			t.Fatalf("Lookup %s failed: %v", name, err)
+		}
+		var buf bytes.Buffer
+		if err := generate.GenerateWith(b, representation, &buf, "example.com/validate"); err != nil {
+			t.Fatalf("GenerateWith %s failed: %v", name, err)
+		}
+		output := buf.String()
+		for _, want := range []string{
+			"opts.setDefaults()",
+			"opts.DecodeError(",
+			"opts.ValidationError(",
+			"opts.HandlerError(",
+			"opts.Encode(",
+			"func DefaultRequestError(",
+			"func DefaultHandlerError(",
+			"func DefaultEncode(",
+		} {
+			if !strings.Contains(output, want) {
+				t.Errorf("%s: generated code is missing %s", name, want)
+			}
+		}
+		if strings.Contains(output, "writeProblem(w, newProblem") || strings.Contains(output, "writeProblem(c, newProblem") {
+			t.Errorf("%s: endpoints must answer errors through the hooks", name)
+		}
+	}
+}
+
+type noContent struct{}
+
+func (h testHandler) Clear(ctx context.Context, param *X) (*noContent, error) { return nil, nil }
+
+func TestGenerateStatus(t *testing.T) {
+	h := testHandler{}
+	app := http.NewAPI()
+	app.Post(h.Post, "desc", http.Status(202))
+	app.Delete(h.Clear, "desc")
+	paths, err := server.ParsePaths(app)
+	if err != nil {
+		t.Fatalf("ParsePaths failed: %v", err)
+	}
+	for name, wants := range map[string][]string{
+		"nethttp": {"opts.Encode(w, r, 202, res)", "w.WriteHeader(204)"},
+		"chi":     {"opts.Encode(w, r, 202, res)", "w.WriteHeader(204)"},
+		"echo":    {"return opts.Encode(c, 202, res)", "return c.NoContent(204)"},
+		"gin":     {"opts.Encode(c, 202, res)", "c.Status(204)"},
+		"fiber":   {"return opts.Encode(c, 202, res)", "return c.SendStatus(204)"},
+	} {
+		b, err := generate.Lookup(name)
+		if err != nil {
+			t.Fatalf("Lookup %s failed: %v", name, err)
+		}
+		var buf bytes.Buffer
+		err = generate.GenerateWith(b, repr.NewRepresentation(paths), &buf, "example.com/validate")
+		if err != nil {
+			t.Fatalf("GenerateWith %s failed: %v", name, err)
+		}
+		output := buf.String()
+		for _, want := range append(wants, "_, err := ") {
+			if !strings.Contains(output, want) {
+				t.Errorf("%s: generated code is missing %s", name, want)
+			}
+		}
+	}
+}
+
+func TestGenerateMethods(t *testing.T) {
+	h := testHandler{}
+	app := http.NewAPI()
+	app.Get(h.Get, "desc", http.AutoHead())
+	app.Handle("PURGE", h.Delete, "desc")
+	sus := app.Static("sus")
+	sus.Put(h.Put, "desc")
+	sus.Options(h.Get, "desc")
+	paths, err := server.ParsePaths(app)
+	if err != nil {
+		t.Fatalf("ParsePaths failed: %v", err)
+	}
+	for name, wants := range map[string][]string{
+		"nethttp": {`"HEAD /{$}"`, `"PURGE /{$}"`, `mux.Handle("OPTIONS /{$}", answerOptions("GET, HEAD, OPTIONS, PURGE"))`},
+		"chi":     {`chi.RegisterMethod("PURGE")`, `r.Method("OPTIONS", "/", answerOptions("GET, HEAD, OPTIONS, PURGE"))`},
+		"echo":    {`e.Add("OPTIONS", "/", answerOptions("GET, HEAD, OPTIONS, PURGE"))`},
+		"gin":     {`r.Handle("OPTIONS", "/", answerOptions("GET, HEAD, OPTIONS, PURGE"))`},
+		"fiber":   {`requireMethods(app, "PURGE")`, `app.Add("OPTIONS", "", answerOptions("GET, HEAD, OPTIONS, PURGE"))`},
+	} {
+		b, err := generate.Lookup(name)
+		if err != nil {
+			t.Fatalf("Lookup %s failed: %v", name, err)
+		}
+		var buf bytes.Buffer
+		err = generate.GenerateWith(b, repr.NewRepresentation(paths), &buf, "example.com/validate")
+		if err != nil {
+			t.Fatalf("GenerateWith %s failed: %v", name, err)
+		}
+		output := buf.String()
+		for _, want := range wants {
+			if !strings.Contains(output, want) {
+				t.Errorf("%s: generated code is missing %s", name, want)
+			}
+		}
+		if strings.Count(output, "answerOptions(\"") != 1 {
+			t.Errorf("%s: paths declaring OPTIONS must not be answered automatically", name)
+		}
+	}
+}
+
+type fileRequest struct {
+	Path string `json:"-" as:"path,path"`
+}
+
+func (h testHandler) GetFile(ctx context.Context, param *fileRequest) (*X, error) { return nil, nil }
+
+func TestGenerateWildcards(t *testing.T) {
+	h := testHandler{}
+	app := http.NewAPI()
+	app.Static("files").Wildcard("path").Get(h.GetFile, "desc")
+	paths, err := server.ParsePaths(app)
+	if err != nil {
+		t.Fatalf("ParsePaths failed: %v", err)
+	}
+	for name, wants := range map[string][]string{
+		"nethttp": {`"GET /files/{path...}"`, `bindParam(&body.Path, r.PathValue("path"))`},
+		"chi":     {`"/files/*"`, `bindParam(&body.Path, r.PathValue("*"))`},
+		"echo":    {`"/files/*"`, `bindParam(&body.Path, c.Param("*"))`},
+		"gin":     {`"/files/*path"`, `bindParam(&body.Path, strings.TrimPrefix(c.Param("path"), "/"))`},
+		"fiber":   {`"/files/*"`, `bindParam(&body.Path, c.Params("*"))`},
+	} {
+		b, err := generate.Lookup(name)
+		if err != nil {
+			t.Fatalf("Lookup %s failed: %v", name, err)
+		}
+		var buf bytes.Buffer
+		err = generate.GenerateWith(b, repr.NewRepresentation(paths), &buf, "example.com/validate")
+		if err != nil {
+			t.Fatalf("GenerateWith %s failed: %v", name, err)
+		}
+		output := buf.String()
+		for _, want := range wants {
+			if !strings.Contains(output, want) {
+				t.Errorf("%s: generated code is missing %s", name, want)
+			}
+		}
+	}
+
+	for client, want := range map[generate.Client]string{
+		generate.GoClient:         `"/files/" + strings.ReplaceAll(url.PathEscape(req.Path), "%2F", "/")`,
+		generate.TypeScriptClient: "`/files/${encodeURIComponent(req.path).replace(/%2F/g, \"/\")}`",
+	} {
+		var buf bytes.Buffer
+		err = generate.GenerateClient(client, repr.NewRepresentation(paths), &buf, "client")
+		if err != nil {
+			t.Fatalf("GenerateClient %s failed: %v", client.Name, err)
+		}
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("%s: generated client is missing %s", client.Name, want)
+		}
+	}
+}
+
+func TestGenerateAuthorization(t *testing.T) {
+	h := testHandler{}
+	app := http.NewAPI()
+	app.Get(h.Get, "desc")
+	app.Static("sus").Delete(h.Delete, "desc", http.Authz("admin", "sus:delete"))
+	paths, err := server.ParsePaths(app)
+	if err != nil {
+		t.Fatalf("ParsePaths failed: %v", err)
+	}
+	for _, name := range generate.Backends() {
+		b, err := generate.Lookup(name)
+		if err != nil {
+			t.Fatalf("Lookup %s failed: %v", name, err)
+		}
+		var buf bytes.Buffer
+		err = generate.GenerateWith(b, repr.NewRepresentation(paths), &buf, "example.com/validate")
+		if err != nil {
+			t.Fatalf("GenerateWith %s failed: %v", name, err)
+		}
+		output := buf.String()
+		if strings.Count(output, "opts.Authorizer.Authorize(") != 1 {
+			t.Errorf("%s: only the endpoint declared with Authz must be authorized", name)
+		}
+		for _, want := range []string{
+			`[]string{"admin", "sus:delete"}`,
+			"if opts.Authorizer == nil {",
+			"http.StatusUnauthorized",
+		} {
+			if !strings.Contains(output, want) {
+				t.Errorf("%s: generated code is missing %s", name, want)
+			}
+		}
+	}
+}
+
+func TestGenerateCustomTemplate(t *testing.T) {
+	fsys := fstest.MapFS{
+		"server.tmpl": {Data: []byte(`{{ define "setup" }}{{ range .Endpoints }}{{ .Method }} {{ .Path | pathToString }}
+{{ end }}{{ end }}`)},
+		"broken.tmpl": {Data: []byte(`{{ define "other" }}{{ end }}`)},
+	}
+
+	backend, err := generate.FromFS(generate.FiberBackend, fsys, "server.tmpl")
+	if err != nil {
+		t.Fatalf("FromFS failed: %v", err)
+	}
+	var buf bytes.Buffer
+	err = generate.GenerateWith(backend, testRoutes(t), &buf, "")
+	if err != nil {
+		t.Fatalf("GenerateWith failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "PATCH /sus/:wus") {
+		t.Errorf("custom template output is missing route, got:\n%s", buf.String())
+	}
+
+	backend, err = generate.FromFS(generate.FiberBackend, fsys, "broken.tmpl")
+	if err != nil {
+		t.Fatalf("FromFS failed: %v", err)
+	}
+	err = generate.GenerateWith(backend, testRoutes(t), &buf, "")
+	if !errors.Is(err, generate.ErrNoSetupTemplate) {
+		t.Errorf("expected ErrNoSetupTemplate, got: %v", err)
+	}
+}
+
+func TestRegisterBackend(t *testing.T) {
+	if err := generate.Register(generate.FiberBackend); !errors.Is(err, generate.ErrBackendExists) {
+		t.Errorf("expected ErrBackendExists, got: %v", err)
+	}
+	if _, err := generate.Lookup("unknown"); err == nil {
+		t.Error("expected Lookup of an unknown backend to fail")
+	}
+}
+
+type GetRequest struct {
+	ID    string `json:"id" as:"id,path"`
+	Limit int    `json:"limit" as:"limit,query"`
+	Token string `json:"-" as:"x-token,header"`
+}
+
+func (h testHandler) GetByID(ctx context.Context, param *GetRequest) (*X, error) { return nil, nil }
+func (h testHandler) Get2(ctx context.Context, param *X) (*X, error)             { return nil, nil }
+
+func TestGenerateClient(t *testing.T) {
+	h := testHandler{}
+	app := http.NewAPI()
+	app.Get(h.Get, "desc")
+	sus := app.Static("sus")
+	sus.Get(h.Get, "desc")
+	id := sus.Param("id")
+	id.Get(h.GetByID, "desc")
+	app.Static("v2").Get(h.Get2, "desc")
+	paths, err := server.ParsePaths(app)
+	if err != nil {
+		t.Fatalf("ParsePaths failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	err = generate.GenerateClient(generate.GoClient, repr.NewRepresentation(paths), &buf, "client")
+	if err != nil {
+		t.Fatalf("GenerateClient failed: %v", err)
+	}
+
+	output := buf.String()
+	if _, err := parser.ParseFile(token.NewFileSet(), "", output, 0); err != nil {
+		t.Fatalf("generated code is not valid Go: %v\n%s", err, output)
+	}
+	for _, want := range []string{
+		"func (c *Client) Get(",
+		"func (c *Client) Get2(",
+		"func (c *Client) Get3(",
+		"func (c *Client) GetByID(ctx context.Context, req *ai.GetRequest) (*ai.X, error)",
+		`"/sus/" + url.PathEscape(formatParam(req.ID))`,
+		`setQuery(query, "limit", req.Limit)`,
+		`setHeader(httpReq.Header, "x-token", req.Token)`,
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("generated client is missing %s", want)
+		}
+	}
+	if n := strings.Count(output, "func (c *Client) Get2("); n != 1 {
+		t.Errorf("repeated names must not take the name of another handler, Get2 declared %d times", n)
+	}
+}
+
+type treeNode struct {
+	Name     string     `json:"name"`
+	Children []treeNode `json:"children"`
+}
+
+type menu struct {
+	Root treeNode `json:"root"`
+}
+
+func (h testHandler) Menu(ctx context.Context, param *X) (*menu, error) { return nil, nil }
+
+func TestGenerateTypeScriptRecursiveTypes(t *testing.T) {
+	h := testHandler{}
+	app := http.NewAPI()
+	app.Get(h.Menu, "desc")
+	paths, err := server.ParsePaths(app)
+	if err != nil {
+		t.Fatalf("ParsePaths failed: %v", err)
+	}
+	var buf bytes.Buffer
+	err = generate.GenerateClient(generate.TypeScriptClient, repr.NewRepresentation(paths), &buf, "client")
+	if err != nil {
+		t.Fatalf("GenerateClient failed: %v", err)
+	}
+	output := buf.String()
+	for _, want := range []string{
+		"export interface treeNode {",
+		"children: Array<treeNode>;",
+		"root: treeNode;",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("generated client is missing %s", want)
+		}
+	}
+}
+
+func TestGenerateTypeScriptClient(t *testing.T) {
+	h := testHandler{}
+	app := http.NewAPI()
+	sus := app.Static("sus")
+	sus.Post(h.Post, "desc")
+	id := sus.Param("id")
+	id.Get(h.GetByID, "desc")
+	paths, err := server.ParsePaths(app)
+	if err != nil {
+		t.Fatalf("ParsePaths failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	err = generate.GenerateClient(generate.TypeScriptClient, repr.NewRepresentation(paths), &buf, "")
+	if err != nil {
+		t.Fatalf("GenerateClient failed: %v", err)
+	}
+
+	output := buf.String()
+	for _, want := range []string{
+		"export interface GetRequest {\n  id: string;\n  limit: number;\n  \"x-token\": string;\n}",
+		"export interface X {\n  y: string;\n}",
+		"export async function getByID(",
+		"): Promise<X> {",
+		"`/sus/${encodeURIComponent(String(req.id))}`",
+		`setParam(query, "limit", req.limit);`,
+		`setParam(headers, "x-token", req["x-token"]);`,
+		`omit(req, []),`,
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("generated client is missing %s", want)
+		}
+	}
+}
+
+type searchRequest struct {
+	Wait  time.Duration `json:"-" as:"wait,query"`
+	Since *time.Time    `json:"since"`
+	Debug bool          `json:"-"`
+}
+
+type searchResponse struct {
+	Hits  []X  `json:"hits"`
+	Best  *X   `json:"best"`
+	Total int  `json:"-" as:"x-total,header"`
+	Exact bool `json:"-" as:"x-exact,header"`
+}
+
+func (h testHandler) Search(ctx context.Context, param *searchRequest) (*searchResponse, error) {
+	return nil, nil
+}
+
+func TestGenerateTypeScriptClientTypes(t *testing.T) {
+	h := testHandler{}
+	app := http.NewAPI()
+	app.Get(h.Get, "desc")
+	app.Static("search").Post(h.Search, "desc")
+	paths, err := server.ParsePaths(app)
+	if err != nil {
+		t.Fatalf("ParsePaths failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	err = generate.GenerateClient(generate.TypeScriptClient, repr.NewRepresentation(paths), &buf, "")
+	if err != nil {
+		t.Fatalf("GenerateClient failed: %v", err)
+	}
+
+	output := buf.String()
+	for _, want := range []string{
+		"  wait: string;\n  since?: string | null;\n",
+		"  hits: Array<X>;\n  best?: X | null;\n  \"x-total\": number;\n  \"x-exact\": boolean;\n",
+		"readParam(res.headers, \"x-total\", (value) => {\n    res.body[\"x-total\"] = Number(value);\n  });",
+		"res.body[\"x-exact\"] = value === \"true\";",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("generated client is missing %s", want)
+		}
+	}
+	if strings.Contains(output, `"-"`) {
+		t.Errorf("fields left out of JSON must not be declared, got:\n%s", output)
+	}
+}
+
+func TestGenerateTypeScriptReservedNames(t *testing.T) {
+	h := testHandler{}
+	app := http.NewAPI()
+	app.Delete(h.Delete, "desc")
+	paths, err := server.ParsePaths(app)
+	if err != nil {
+		t.Fatalf("ParsePaths failed: %v", err)
+	}
+	var buf bytes.Buffer
+	err = generate.GenerateClient(generate.TypeScriptClient, repr.NewRepresentation(paths), &buf, "")
+	if err != nil {
+		t.Fatalf("GenerateClient failed: %v", err)
+	}
+	if output := buf.String(); !strings.Contains(output, "export async function delete_(") {
+		t.Errorf("reserved words must be escaped, got:\n%s", output)
+	}
+}
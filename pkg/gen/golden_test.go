@@ -0,0 +1,117 @@
+package generate_test
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/simplicity-load/apispec/pkg/gen"
+	"github.com/simplicity-load/apispec/pkg/gen/openapi"
+	"github.com/simplicity-load/apispec/pkg/gen/testdata/golden"
+	"github.com/simplicity-load/apispec/pkg/http"
+	"github.com/simplicity-load/apispec/pkg/parse/server"
+	repr "github.com/simplicity-load/apispec/pkg/repr/http"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+// goldenRoutes covers the features rendered by every generator.
+func goldenRoutes(t *testing.T) repr.Representation {
+	t.Helper()
+	h := golden.Handler{}
+	app := http.NewAPI()
+	app.MapErrors(http.ErrorIs(golden.ErrNotFound, 404))
+	users := app.Static("users")
+	users.Tag("users")
+	users.Get(h.ListUsers, "List users")
+	users.Post(h.CreateUser, "Create a user", http.Status(201), http.Errors(http.ErrorAs[*golden.ConflictError](409)))
+	id := users.Param("id")
+	id.Get(h.GetUser, "Get a user", http.OperationID("getUser"), http.Summary("Get user"), http.AutoHead())
+	id.Delete(h.DeleteUser, "Delete a user", http.Authz("admin"), http.Tags("admin"), http.Deprecated())
+	paths, err := server.ParsePaths(app)
+	if err != nil {
+		t.Fatalf("ParsePaths failed: %v", err)
+	}
+	return repr.NewRepresentation(paths)
+}
+
+// TestGolden renders every backend, client and the OpenAPI document of
+// testRoutes repeatedly, the output must be identical on every run and match
+// testdata/<name>.golden. Run with -update to rewrite them.
+func TestGolden(t *testing.T) {
+	renderers := map[string]func(*bytes.Buffer) error{
+		"openapi.json": func(buf *bytes.Buffer) error {
+			return openapi.Generate(goldenRoutes(t).Routes, buf, "Test API", "1.0.0", "")
+		},
+		"openapi.yaml": func(buf *bytes.Buffer) error {
+			return openapi.GenerateYAML(goldenRoutes(t).Routes, buf, "Test API", "1.0.0", "")
+		},
+	}
+	for _, name := range generate.Backends() {
+		renderers[name+".go"] = func(buf *bytes.Buffer) error {
+			b, err := generate.Lookup(name)
+			if err != nil {
+				return err
+			}
+			return generate.GenerateWith(b, goldenRoutes(t), buf, "example.com/validate")
+		}
+	}
+	for name, ext := range map[string]string{"go": ".go", "typescript": ".ts"} {
+		renderers["client_"+name+ext] = func(buf *bytes.Buffer) error {
+			c, err := generate.LookupClient(name)
+			if err != nil {
+				return err
+			}
+			return generate.GenerateClient(c, goldenRoutes(t), buf, "client")
+		}
+	}
+
+	for name, render := range renderers {
+		t.Run(name, func(t *testing.T) {
+			var first bytes.Buffer
+			if err := render(&first); err != nil {
+				t.Fatalf("render failed: %v", err)
+			}
+			for range 10 {
+				var buf bytes.Buffer
+				if err := render(&buf); err != nil {
+					t.Fatalf("render failed: %v", err)
+				}
+				if !bytes.Equal(first.Bytes(), buf.Bytes()) {
+					t.Fatal("output differs between runs")
+				}
+			}
+
+			golden := filepath.Join("testdata", name+".golden")
+			if *update {
+				if err := os.WriteFile(golden, first.Bytes(), 0o644); err != nil {
+					t.Fatalf("update golden file failed: %v", err)
+				}
+				return
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("read golden file failed: %v", err)
+			}
+			if !bytes.Equal(want, first.Bytes()) {
+				t.Errorf("output differs from %s, run with -update if intended", golden)
+			}
+		})
+	}
+}
+
+// TestGoldenCompiles vets the Go golden files against the frameworks they
+// import.
+func TestGoldenCompiles(t *testing.T) {
+	files := map[string]string{"validate/validate.go": validateStub}
+	for _, name := range append(generate.Backends(), "client_go") {
+		src, err := os.ReadFile(filepath.Join("testdata", name+".go.golden"))
+		if err != nil {
+			t.Fatalf("read golden file failed: %v", err)
+		}
+		files[filepath.Join(name, name+".go")] = string(src)
+	}
+	runModule(t, files)
+}
@@ -1,11 +1,13 @@
 package openapi
 
 import (
+	"cmp"
 	"encoding/json"
 	"fmt"
 	"io"
 	"reflect"
 	"slices"
+	"strconv"
 	"strings"
 
 	repr "github.com/simplicity-load/apispec/pkg/repr/http"
@@ -13,6 +15,35 @@ import (
 
 // Generate creates an OpenAPI v3.1 specification from the parsed routes
 func Generate(routes *repr.Path, output io.Writer, title, version, serverURL string) error {
+	spec, err := build(routes, title, version, serverURL)
+	if err != nil {
+		return err
+	}
+
+	// Write JSON output
+	encoder := json.NewEncoder(output)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(spec); err != nil {
+		return fmt.Errorf("failed to encode OpenAPI spec: %w", err)
+	}
+
+	return nil
+}
+
+// GenerateYAML is [Generate] writing YAML, with the keys in the order of the
+// JSON document.
+func GenerateYAML(routes *repr.Path, output io.Writer, title, version, serverURL string) error {
+	spec, err := build(routes, title, version, serverURL)
+	if err != nil {
+		return err
+	}
+	if err := writeYAML(output, spec); err != nil {
+		return fmt.Errorf("failed to encode OpenAPI spec: %w", err)
+	}
+	return nil
+}
+
+func build(routes *repr.Path, title, version, serverURL string) (*OpenAPI, error) {
 	spec := &OpenAPI{
 		OpenAPI: "3.1.0",
 		Info: Info{
@@ -29,22 +60,117 @@ func Generate(routes *repr.Path, output io.Writer, title, version, serverURL str
 	}
 
 	// Convert routes to OpenAPI paths
-	if err := convertPaths(routes, spec.Paths, ""); err != nil {
-		return fmt.Errorf("failed to convert paths: %w", err)
+	c := newComponents(routes)
+	if err := convertPaths(c, routes, spec.Paths, ""); err != nil {
+		return nil, fmt.Errorf("failed to convert paths: %w", err)
+	}
+	if len(c.schemas) > 0 || len(c.securitySchemes) > 0 {
+		spec.Components = &Components{
+			Schemas:         c.schemas,
+			SecuritySchemes: c.securitySchemes,
+		}
 	}
+	return spec, nil
+}
 
-	// Write JSON output
-	encoder := json.NewEncoder(output)
-	encoder.SetIndent("", "  ")
-	if err := encoder.Encode(spec); err != nil {
-		return fmt.Errorf("failed to encode OpenAPI spec: %w", err)
+// components holds the named types of the spec, every type is emitted once
+// under components.schemas and referenced by $ref.
+type components struct {
+	names           map[repr.TypeKey]string
+	schemas         map[string]*Schema
+	securitySchemes map[string]*SecurityScheme
+}
+
+// newComponents names every named type reachable from routes up front, so
+// names don't depend on the order types are converted in.
+func newComponents(routes *repr.Path) *components {
+	keys := make([]repr.TypeKey, 0)
+	var collect func(fields []*repr.StructField)
+	collect = func(fields []*repr.StructField) {
+		for _, field := range fields {
+			if key, ok := field.TypeKey(); ok {
+				keys = append(keys, key)
+			}
+			collect(field.SubFields)
+		}
+	}
+	// the body of error responses, named like any other type so a user type
+	// called Problem gets prefixed instead
+	keys = append(keys, problemKey)
+	for endpoint := range routes.AllEndpoints() {
+		for _, data := range []*repr.Data{endpoint.Body, endpoint.Response} {
+			if key, ok := data.TypeKey(); ok {
+				keys = append(keys, key)
+			}
+			collect(data.Fields)
+		}
 	}
+	return &components{
+		names:           repr.UniqueTypeNames(keys),
+		schemas:         make(map[string]*Schema),
+		securitySchemes: make(map[string]*SecurityScheme),
+	}
+}
 
-	return nil
+// problemKey names the schema of error response bodies, RFC 7807 problem
+// documents.
+var problemKey = repr.TypeKey{Name: "Problem"}
+
+const problemContentType = "application/problem+json"
+
+// securityScheme is the scheme endpoints declared with Authz require, the
+// generated servers leave authentication to their Authorizer.
+const securityScheme = "bearerAuth"
+
+func (c *components) problemSchema() *Schema {
+	return c.ref(problemKey, func() *Schema {
+		return &Schema{
+			Type: "object",
+			Properties: map[string]*Schema{
+				"type":   {Type: "string", Format: "uri-reference"},
+				"title":  {Type: "string"},
+				"status": {Type: "integer"},
+				"detail": {Type: "string"},
+				"errors": {
+					Type: "array",
+					Items: &Schema{
+						Type: "object",
+						Properties: map[string]*Schema{
+							"field":  {Type: "string"},
+							"in":     {Type: "string", Enum: []any{"body", "path", "query", "header", "cookie"}},
+							"rule":   {Type: "string"},
+							"detail": {Type: "string"},
+						},
+						Required: []string{"field", "in"},
+					},
+				},
+			},
+			Required: []string{"type", "title", "status"},
+		}
+	})
+}
+
+// ref returns a reference to the component of key, building it on first use.
+func (c *components) ref(key repr.TypeKey, build func() *Schema) *Schema {
+	name := c.names[key]
+	if _, ok := c.schemas[name]; !ok {
+		c.schemas[name] = &Schema{} // placeholder until built
+		c.schemas[name] = build()
+	}
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// schema resolves a reference to its component.
+func (c *components) schema(s *Schema) *Schema {
+	name, ok := strings.CutPrefix(s.Ref, "#/components/schemas/")
+	if !ok {
+		return s
+	}
+	return c.schemas[name]
 }
 
 // convertPaths recursively converts repr.Path to OpenAPI paths
-func convertPaths(path *repr.Path, paths map[string]PathItem, parentPath string) error {
+func convertPaths(c *components, path *repr.Path, paths map[string]PathItem, parentPath string) error {
 	// Build the path string
 	pathStr := buildPathString(path)
 
@@ -63,7 +189,7 @@ func convertPaths(path *repr.Path, paths map[string]PathItem, parentPath string)
 	if len(path.Endpoints) > 0 {
 		pathItem := paths[fullPath]
 		for _, endpoint := range path.Endpoints {
-			operation := convertEndpoint(endpoint)
+			operation := convertEndpoint(c, endpoint)
 			setOperation(&pathItem, string(endpoint.Method), operation)
 		}
 		paths[fullPath] = pathItem
@@ -71,7 +197,7 @@ func convertPaths(path *repr.Path, paths map[string]PathItem, parentPath string)
 
 	// Process subpaths recursively
 	for _, subPath := range path.SubPath {
-		if err := convertPaths(subPath, paths, fullPath); err != nil {
+		if err := convertPaths(c, subPath, paths, fullPath); err != nil {
 			return err
 		}
 	}
@@ -90,7 +216,7 @@ func buildPathString(path *repr.Path) string {
 
 	if path.Type == repr.PathSTATIC {
 		parts = append(parts, path.Name)
-	} else if path.Type == repr.PathPARAM {
+	} else if path.Type == repr.PathPARAM || path.Type == repr.PathWILDCARD {
 		parts = append(parts, "{"+path.Name+"}")
 	}
 
@@ -102,43 +228,45 @@ func buildPathString(path *repr.Path) string {
 }
 
 // convertEndpoint converts a repr.Endpoint to an OpenAPI Operation
-func convertEndpoint(endpoint *repr.Endpoint) *Operation {
+func convertEndpoint(c *components, endpoint *repr.Endpoint) *Operation {
 	operation := &Operation{
-		Summary:     endpoint.Handler.Name,
-		OperationID: endpoint.Handler.Name,
+		Tags:        endpoint.Tags,
+		Summary:     cmp.Or(endpoint.Summary, endpoint.Handler.Name),
+		Description: endpoint.Description,
+		OperationID: cmp.Or(endpoint.OperationID, endpoint.Handler.Name),
 		Responses:   make(map[string]Response),
+		Deprecated:  endpoint.Deprecated,
 	}
 
-	// Add parameters (path and query)
+	// Add parameters (path, query, header and cookie)
 	var params []Parameter
 	for _, field := range endpoint.Body.Fields {
-		if field.Serialization != nil {
-			switch field.Serialization.Type {
-			case repr.SerializationPATH:
-				params = append(params, Parameter{
-					Name:     field.Serialization.Name,
-					In:       "path",
-					Required: true,
-					Schema:   convertFieldToSchema(field),
-				})
-			case repr.SerializationQUERY:
-				params = append(params, Parameter{
-					Name:     field.Serialization.Name,
-					In:       "query",
-					Required: isRequired(field.Validation),
-					Schema:   convertFieldToSchema(field),
-				})
-			}
+		if field.Serialization == nil {
+			continue
+		}
+		in, ok := parameterLocations[field.Serialization.Type]
+		if !ok {
+			continue
+		}
+		param := Parameter{
+			Name:     field.Serialization.Name,
+			In:       in,
+			Required: field.Serialization.Type == repr.SerializationPATH || isRequired(field.Validation),
+			Schema:   convertParamToSchema(c, field),
 		}
+		if field.Serialization.Wildcard {
+			param.Description = "The rest of the path, slashes included."
+		}
+		params = append(params, param)
 	}
 	if len(params) > 0 {
 		operation.Parameters = params
 	}
 
-	// Add request body for non-GET methods
-	if endpoint.Method != "GET" {
-		bodySchema := convertDataToSchema(endpoint.Body)
-		if bodySchema != nil && len(bodySchema.Properties) > 0 {
+	// Add request body for methods other than GET and HEAD
+	if endpoint.Method != "GET" && endpoint.Method != "HEAD" {
+		bodySchema := convertDataToSchema(c, endpoint.Body)
+		if len(c.schema(bodySchema).Properties) > 0 {
 			operation.RequestBody = &RequestBody{
 				Required: true,
 				Content: map[string]MediaType{
@@ -151,39 +279,151 @@ func convertEndpoint(endpoint *repr.Endpoint) *Operation {
 	}
 
 	// Add response
-	responseSchema := convertDataToSchema(endpoint.Response)
-	operation.Responses["200"] = Response{
+	response := Response{
 		Description: "Successful response",
-		Content: map[string]MediaType{
+		Headers:     convertResponseHeaders(c, endpoint.Response),
+	}
+	if endpoint.HasResponseBody() {
+		response.Content = map[string]MediaType{
 			"application/json": {
-				Schema: responseSchema,
+				Schema: convertDataToSchema(c, endpoint.Response),
 			},
-		},
+		}
 	}
+	operation.Responses[strconv.Itoa(endpoint.Status)] = response
+	// every request may fail to bind or validate, and every handler to
+	// return an unmapped error
+	operation.Responses["400"] = errorResponse(c, "Bad request")
+	operation.Responses["500"] = errorResponse(c, "Internal server error")
+	convertErrors(c, endpoint.Errors, operation.Responses)
+	convertAuthorization(c, endpoint.Authorization, operation)
 
 	return operation
 }
 
-// convertDataToSchema converts repr.Data to an inline OpenAPI Schema
-func convertDataToSchema(data *repr.Data) *Schema {
+var parameterLocations = map[repr.SerializationType]string{
+	repr.SerializationPATH:   "path",
+	repr.SerializationQUERY:  "query",
+	repr.SerializationHEADER: "header",
+	repr.SerializationCOOKIE: "cookie",
+}
+
+// convertResponseHeaders documents the header fields of the response, cookie
+// fields are written as Set-Cookie headers and share its entry.
+func convertResponseHeaders(c *components, data *repr.Data) map[string]Header {
+	if data == nil {
+		return nil
+	}
+	headers := make(map[string]Header)
+	cookies := make([]string, 0)
+	for _, field := range data.Fields {
+		if field.Serialization == nil {
+			continue
+		}
+		switch field.Serialization.Type {
+		case repr.SerializationHEADER:
+			headers[field.Serialization.Name] = Header{Schema: convertParamToSchema(c, field)}
+		case repr.SerializationCOOKIE:
+			cookies = append(cookies, field.Serialization.Name)
+		}
+	}
+	if len(cookies) > 0 {
+		headers["Set-Cookie"] = Header{
+			Description: "Sets the cookies: " + strings.Join(cookies, ", "),
+			Schema:      &Schema{Type: "string"},
+		}
+	}
+	if len(headers) == 0 {
+		return nil
+	}
+	return headers
+}
+
+// convertErrors adds a response per status errors are mapped to, described
+// by the errors mapped to it in order.
+func convertErrors(c *components, errs []*repr.ErrorStatus, responses map[string]Response) {
+	descriptions := make(map[int][]string)
+	for _, es := range errs {
+		if !slices.Contains(descriptions[es.Status], es.Description()) {
+			descriptions[es.Status] = append(descriptions[es.Status], es.Description())
+		}
+	}
+	for status, descs := range descriptions {
+		responses[strconv.Itoa(status)] = errorResponse(c, strings.Join(descs, ", "))
+	}
+}
+
+func errorResponse(c *components, description string) Response {
+	return Response{
+		Description: description,
+		Content: map[string]MediaType{
+			problemContentType: {
+				Schema: c.problemSchema(),
+			},
+		},
+	}
+}
+
+// convertAuthorization requires the security scheme with the permissions of
+// authorization, answered with 401 when unauthenticated and 403 when lacking
+// them.
+func convertAuthorization(c *components, authorization []string, operation *Operation) {
+	if len(authorization) == 0 {
+		return
+	}
+	c.securitySchemes[securityScheme] = &SecurityScheme{
+		Type:   "http",
+		Scheme: "bearer",
+	}
+	operation.Security = []SecurityRequirement{
+		{securityScheme: slices.Clone(authorization)},
+	}
+	operation.Responses["401"] = errorResponse(c, "Unauthenticated")
+	operation.Responses["403"] = errorResponse(c, "Forbidden")
+}
+
+// convertParamToSchema converts a field bound outside of the JSON body,
+// where durations are written like "1h30m" rather than in ISO 8601, so they
+// carry no format.
+func convertParamToSchema(c *components, field *repr.StructField) *Schema {
+	if field.Format == "duration" {
+		return &Schema{Type: "string"}
+	}
+	return convertFieldToSchema(c, field)
+}
+
+// convertDataToSchema converts repr.Data to a reference to its JSON body,
+// anonymous structs are inlined
+func convertDataToSchema(c *components, data *repr.Data) *Schema {
 	if data == nil {
 		return &Schema{Type: "object"}
 	}
+	if key, ok := data.TypeKey(); ok {
+		return c.ref(key, func() *Schema { return convertFieldsToSchema(c, data.Fields) })
+	}
+	return convertFieldsToSchema(c, data.Fields)
+}
 
+// convertFieldsToSchema converts the JSON fields of a struct to an inline
+// OpenAPI Schema, every struct is converted through it so a named type has
+// the same schema wherever it's first met.
+func convertFieldsToSchema(c *components, fields []*repr.StructField) *Schema {
 	schema := &Schema{
 		Type:       "object",
 		Properties: make(map[string]*Schema),
 	}
 
 	var required []string
-	for _, field := range data.Fields {
-		// Skip apispec params as they're handled separately
+	for _, field := range fields {
+		// Skip apispec params as they're handled separately, and fields
+		// left out of JSON
 		if field.Serialization != nil &&
-			slices.Contains(repr.ApiSpecSerializationTypes, field.Serialization.Type) {
+			(slices.Contains(repr.ApiSpecSerializationTypes, field.Serialization.Type) ||
+				field.Serialization.Name == "-") {
 			continue
 		}
 
-		fieldSchema := convertFieldToSchema(field)
+		fieldSchema := convertFieldToSchema(c, field)
 		fieldName := field.Name
 		if field.Serialization != nil {
 			fieldName = field.Serialization.Name
@@ -203,16 +443,22 @@ func convertDataToSchema(data *repr.Data) *Schema {
 	return schema
 }
 
-// convertFieldToSchema converts a repr.StructField to an OpenAPI Schema
-func convertFieldToSchema(field *repr.StructField) *Schema {
+// convertFieldToSchema converts a repr.StructField to an OpenAPI Schema,
+// named structs are referenced
+func convertFieldToSchema(c *components, field *repr.StructField) *Schema {
 	schema := &Schema{}
 
 	switch field.Type {
 	case reflect.String:
 		schema.Type = "string"
+		schema.Format = field.Format
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
 		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
 		schema.Type = "integer"
+		if field.Format == "duration" {
+			// durations are JSON encoded as nanoseconds
+			schema.Format = "int64"
+		}
 	case reflect.Float32, reflect.Float64:
 		schema.Type = "number"
 	case reflect.Bool:
@@ -220,43 +466,42 @@ func convertFieldToSchema(field *repr.StructField) *Schema {
 	case reflect.Array, reflect.Slice:
 		schema.Type = "array"
 		if len(field.SubFields) > 0 {
-			schema.Items = convertFieldToSchema(field.SubFields[0])
+			schema.Items = convertFieldToSchema(c, field.SubFields[0])
 		} else {
 			schema.Items = &Schema{Type: "string"}
 		}
 	case reflect.Struct:
-		schema.Type = "object"
-		schema.Properties = make(map[string]*Schema)
-		var required []string
-		for _, subField := range field.SubFields {
-			subFieldName := subField.Name
-			if subField.Serialization != nil {
-				subFieldName = subField.Serialization.Name
-			}
-			schema.Properties[subFieldName] = convertFieldToSchema(subField)
-			if isRequired(subField.Validation) {
-				required = append(required, subFieldName)
-			}
-		}
-		if len(required) > 0 {
-			schema.Required = required
+		if key, ok := field.TypeKey(); ok {
+			return c.ref(key, func() *Schema { return convertFieldsToSchema(c, field.SubFields) })
 		}
+		return convertFieldsToSchema(c, field.SubFields)
 	case reflect.Map:
 		schema.Type = "object"
-		// For maps, we use additionalProperties pattern
+		// SubFields[0] is the key, SubFields[1] is the value
 		if len(field.SubFields) > 1 {
-			// SubFields[0] is the key, SubFields[1] is the value
-			// OpenAPI doesn't directly support typed keys, so we just type the values
-			schema.Properties = make(map[string]*Schema)
+			schema.PropertyNames = convertMapKeyToSchema(field.SubFields[0])
+			schema.AdditionalProperties = convertFieldToSchema(c, field.SubFields[1])
 		}
 	default:
 		schema.Type = "string"
 	}
+	applyValidation(schema, field, field.Validation)
 
 	return schema
 }
 
-// setOperation sets the operation on the path item based on HTTP method
+// convertMapKeyToSchema documents the keys of maps, JSON encodes integer
+// keys as decimal strings. String keys need no schema.
+func convertMapKeyToSchema(key *repr.StructField) *Schema {
+	switch key.Type {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &Schema{Type: "string", Pattern: "^-?[0-9]+$"}
+	}
+	return nil
+}
+
+// setOperation sets the operation on the path item based on HTTP method,
+// custom methods are set as extensions.
 func setOperation(pathItem *PathItem, method string, operation *Operation) {
 	switch strings.ToUpper(method) {
 	case "GET":
@@ -269,14 +514,30 @@ func setOperation(pathItem *PathItem, method string, operation *Operation) {
 		pathItem.Patch = operation
 	case "DELETE":
 		pathItem.Delete = operation
+	case "HEAD":
+		pathItem.Head = operation
+	case "OPTIONS":
+		pathItem.Options = operation
+	case "TRACE":
+		pathItem.Trace = operation
+	default:
+		if pathItem.AdditionalOperations == nil {
+			pathItem.AdditionalOperations = make(map[string]*Operation)
+		}
+		pathItem.AdditionalOperations[method] = operation
 	}
 }
 
-// isRequired checks if a field has "required" in its validation tags
+// isRequired checks if a field has "required" in its validation tags, rules
+// after dive or keys apply to its items, keys or values instead, see
+// [applyValidation].
 func isRequired(validation []string) bool {
 	for _, v := range validation {
-		if v == "required" {
+		switch v {
+		case "required":
 			return true
+		case "dive", "keys":
+			return false
 		}
 	}
 	return false
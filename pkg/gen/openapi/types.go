@@ -1,6 +1,8 @@
 package openapi
 
-// OpenAPI v3.1 type definitions (simplified, inline schemas only)
+import "encoding/json"
+
+// OpenAPI v3.1 type definitions (simplified)
 
 type OpenAPI struct {
 	OpenAPI    string              `json:"openapi"`
@@ -21,24 +23,46 @@ type Server struct {
 }
 
 type PathItem struct {
-	Get    *Operation `json:"get,omitempty"`
-	Post   *Operation `json:"post,omitempty"`
-	Put    *Operation `json:"put,omitempty"`
-	Patch  *Operation `json:"patch,omitempty"`
-	Delete *Operation `json:"delete,omitempty"`
+	Get     *Operation `json:"get,omitempty"`
+	Post    *Operation `json:"post,omitempty"`
+	Put     *Operation `json:"put,omitempty"`
+	Patch   *Operation `json:"patch,omitempty"`
+	Delete  *Operation `json:"delete,omitempty"`
+	Head    *Operation `json:"head,omitempty"`
+	Options *Operation `json:"options,omitempty"`
+	Trace   *Operation `json:"trace,omitempty"`
+	// AdditionalOperations holds the operations of custom methods by method,
+	// OpenAPI 3.1 has no field for them, the extension mirrors the
+	// additionalOperations of OpenAPI 3.2.
+	AdditionalOperations map[string]*Operation `json:"x-additionalOperations,omitempty"`
 }
 
 type Operation struct {
-	Summary     string              `json:"summary,omitempty"`
-	OperationID string              `json:"operationId,omitempty"`
-	Parameters  []Parameter         `json:"parameters,omitempty"`
-	RequestBody *RequestBody        `json:"requestBody,omitempty"`
-	Responses   map[string]Response `json:"responses"`
+	Tags        []string              `json:"tags,omitempty"`
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	OperationID string                `json:"operationId,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]Response   `json:"responses"`
+	Deprecated  bool                  `json:"deprecated,omitempty"`
+	Security    []SecurityRequirement `json:"security,omitempty"`
+}
+
+// SecurityRequirement maps security scheme names to the permissions required
+// of them.
+type SecurityRequirement map[string][]string
+
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	Description  string `json:"description,omitempty"`
 }
 
 type Parameter struct {
 	Name        string  `json:"name"`
-	In          string  `json:"in"` // "query", "path", "header"
+	In          string  `json:"in"` // "query", "path", "header", "cookie"
 	Required    bool    `json:"required,omitempty"`
 	Schema      *Schema `json:"schema"`
 	Description string  `json:"description,omitempty"`
@@ -51,21 +75,48 @@ type RequestBody struct {
 
 type Response struct {
 	Description string               `json:"description"`
+	Headers     map[string]Header    `json:"headers,omitempty"`
 	Content     map[string]MediaType `json:"content,omitempty"`
 }
 
+type Header struct {
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
 type MediaType struct {
 	Schema *Schema `json:"schema"`
 }
 
 type Schema struct {
+	Ref        string             `json:"$ref,omitempty"`
 	Type       string             `json:"type,omitempty"`
 	Properties map[string]*Schema `json:"properties,omitempty"`
 	Items      *Schema            `json:"items,omitempty"`
 	Required   []string           `json:"required,omitempty"`
 	Format     string             `json:"format,omitempty"`
+	Enum       []any              `json:"enum,omitempty"`
+	Pattern    string             `json:"pattern,omitempty"`
+
+	// AdditionalProperties and PropertyNames describe the values and keys of
+	// maps.
+	AdditionalProperties *Schema `json:"additionalProperties,omitempty"`
+	PropertyNames        *Schema `json:"propertyNames,omitempty"`
+
+	// Constraints translated from validate tags
+	Minimum          json.Number `json:"minimum,omitempty"`
+	Maximum          json.Number `json:"maximum,omitempty"`
+	ExclusiveMinimum json.Number `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum json.Number `json:"exclusiveMaximum,omitempty"`
+	MinLength        *int        `json:"minLength,omitempty"`
+	MaxLength        *int        `json:"maxLength,omitempty"`
+	MinItems         *int        `json:"minItems,omitempty"`
+	MaxItems         *int        `json:"maxItems,omitempty"`
+	MinProperties    *int        `json:"minProperties,omitempty"`
+	MaxProperties    *int        `json:"maxProperties,omitempty"`
 }
 
 type Components struct {
-	Schemas map[string]*Schema `json:"schemas,omitempty"`
+	Schemas         map[string]*Schema         `json:"schemas,omitempty"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
 }
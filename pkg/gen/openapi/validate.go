@@ -0,0 +1,136 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	repr "github.com/simplicity-load/apispec/pkg/repr/http"
+)
+
+// validationFormats maps validator rules checking a string's format to the
+// format of its schema.
+var validationFormats = map[string]string{
+	"email":    "email",
+	"url":      "uri",
+	"uri":      "uri",
+	"uuid":     "uuid",
+	"uuid3":    "uuid",
+	"uuid4":    "uuid",
+	"uuid5":    "uuid",
+	"ipv4":     "ipv4",
+	"ipv6":     "ipv6",
+	"hostname": "hostname",
+}
+
+// applyValidation translates the validator rules of a field into constraints
+// of its schema. Rules after dive apply to the items, rules the schema can't
+// express, e.g. alternatives with "|" or cross field checks, are left out.
+func applyValidation(schema *Schema, field *repr.StructField, rules []string) {
+	if schema.Ref != "" {
+		return
+	}
+	for i := 0; i < len(rules); i++ {
+		if strings.Contains(rules[i], "|") {
+			continue
+		}
+		name, param, _ := strings.Cut(rules[i], "=")
+		switch name {
+		case "dive":
+			switch {
+			case schema.Items != nil && len(field.SubFields) > 0:
+				applyValidation(schema.Items, field.SubFields[0], rules[i+1:])
+			case schema.AdditionalProperties != nil && len(field.SubFields) > 1:
+				applyMapValidation(schema, field, rules[i+1:])
+			}
+			return
+		case "min", "gte":
+			applyBound(schema, param, 0, &schema.Minimum, &schema.MinLength, &schema.MinItems, &schema.MinProperties)
+		case "max", "lte":
+			applyBound(schema, param, 0, &schema.Maximum, &schema.MaxLength, &schema.MaxItems, &schema.MaxProperties)
+		case "gt":
+			applyBound(schema, param, 1, &schema.ExclusiveMinimum, &schema.MinLength, &schema.MinItems, &schema.MinProperties)
+		case "lt":
+			applyBound(schema, param, -1, &schema.ExclusiveMaximum, &schema.MaxLength, &schema.MaxItems, &schema.MaxProperties)
+		case "len":
+			applyBound(schema, param, 0, &schema.Minimum, &schema.MinLength, &schema.MinItems, &schema.MinProperties)
+			applyBound(schema, param, 0, &schema.Maximum, &schema.MaxLength, &schema.MaxItems, &schema.MaxProperties)
+		case "oneof":
+			schema.Enum = enumValues(schema, param)
+		default:
+			if format, ok := validationFormats[name]; ok && schema.Type == "string" {
+				schema.Format = format
+			}
+		}
+	}
+}
+
+// applyMapValidation applies the rules following a map's dive, the ones
+// between keys and endkeys to its keys and the rest to its values.
+func applyMapValidation(schema *Schema, field *repr.StructField, rules []string) {
+	if len(rules) == 0 || rules[0] != "keys" {
+		applyValidation(schema.AdditionalProperties, field.SubFields[1], rules)
+		return
+	}
+	end := slices.Index(rules, "endkeys")
+	if end < 0 {
+		return
+	}
+	// integer keys are already bound by their pattern, the validator compares
+	// their values rather than lengths
+	if field.SubFields[0].Type == reflect.String {
+		if schema.PropertyNames == nil {
+			schema.PropertyNames = &Schema{Type: "string"}
+		}
+		applyValidation(schema.PropertyNames, field.SubFields[0], rules[1:end])
+	}
+	applyValidation(schema.AdditionalProperties, field.SubFields[1], rules[end+1:])
+}
+
+// applyBound sets the bound of schema's type to param. The validator bounds
+// the value of numbers and the length of everything else, offset turns
+// exclusive length bounds inclusive.
+func applyBound(schema *Schema, param string, offset int, number *json.Number, length, items, properties **int) {
+	if schema.Type == "integer" || schema.Type == "number" {
+		if _, err := strconv.ParseFloat(param, 64); err == nil {
+			*number = json.Number(param)
+		}
+		return
+	}
+	n, err := strconv.Atoi(param)
+	if err != nil {
+		return
+	}
+	n += offset
+	switch schema.Type {
+	case "string":
+		*length = &n
+	case "array":
+		*items = &n
+	case "object":
+		*properties = &n
+	}
+}
+
+// oneofValues splits like the validator does, single quotes group values
+// containing spaces.
+var oneofValues = regexp.MustCompile(`'[^']*'|\S+`)
+
+func enumValues(schema *Schema, param string) []any {
+	values := make([]any, 0)
+	for _, value := range oneofValues.FindAllString(param, -1) {
+		value = strings.Trim(value, "'")
+		if schema.Type == "integer" || schema.Type == "number" {
+			if _, err := strconv.ParseFloat(value, 64); err != nil {
+				continue
+			}
+			values = append(values, json.Number(value))
+			continue
+		}
+		values = append(values, value)
+	}
+	return values
+}
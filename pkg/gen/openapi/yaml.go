@@ -0,0 +1,161 @@
+package openapi
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// yamlNode is a JSON value with the order of object keys preserved.
+type yamlNode struct {
+	// scalar is the YAML form of strings, numbers, booleans and null.
+	scalar string
+	keys   []string
+	values []*yamlNode
+	object bool
+	array  bool
+}
+
+// writeYAML writes v as a YAML block document. v is encoded with
+// encoding/json first, so the document has the keys, and key order, of the
+// JSON output: struct fields in declaration order and map keys sorted.
+func writeYAML(output io.Writer, v any) error {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(&buf)
+	decoder.UseNumber()
+	root, err := decodeYAMLNode(decoder)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(output)
+	switch {
+	case root.object && len(root.keys) > 0:
+		writeYAMLMapping(w, root, 0, false)
+	case root.array && len(root.values) > 0:
+		writeYAMLSequence(w, root, 0)
+	default:
+		fmt.Fprintln(w, inlineYAML(root))
+	}
+	return w.Flush()
+}
+
+func decodeYAMLNode(decoder *json.Decoder) (*yamlNode, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch t := token.(type) {
+	case json.Delim:
+		node := &yamlNode{object: t == '{', array: t == '['}
+		for decoder.More() {
+			if node.object {
+				key, err := decoder.Token()
+				if err != nil {
+					return nil, err
+				}
+				node.keys = append(node.keys, key.(string))
+			}
+			value, err := decodeYAMLNode(decoder)
+			if err != nil {
+				return nil, err
+			}
+			node.values = append(node.values, value)
+		}
+		// closing delimiter
+		if _, err := decoder.Token(); err != nil {
+			return nil, err
+		}
+		return node, nil
+	case string:
+		return &yamlNode{scalar: quoteYAML(t)}, nil
+	case json.Number:
+		return &yamlNode{scalar: t.String()}, nil
+	case bool:
+		return &yamlNode{scalar: fmt.Sprint(t)}, nil
+	case nil:
+		return &yamlNode{scalar: "null"}, nil
+	}
+	return nil, fmt.Errorf("unexpected JSON token: %v", token)
+}
+
+// writeYAMLMapping writes the entries of node indented by indent, the first
+// one continues the current line when inline, after a sequence's "- ".
+func writeYAMLMapping(w *bufio.Writer, node *yamlNode, indent int, inline bool) {
+	for i, key := range node.keys {
+		if i > 0 || !inline {
+			w.WriteString(strings.Repeat(" ", indent))
+		}
+		w.WriteString(quoteYAML(key) + ":")
+		writeYAMLValue(w, node.values[i], indent)
+	}
+}
+
+func writeYAMLSequence(w *bufio.Writer, node *yamlNode, indent int) {
+	for _, value := range node.values {
+		w.WriteString(strings.Repeat(" ", indent) + "-")
+		if value.object && len(value.keys) > 0 {
+			w.WriteString(" ")
+			writeYAMLMapping(w, value, indent+2, true)
+			continue
+		}
+		writeYAMLValue(w, value, indent)
+	}
+}
+
+// writeYAMLValue completes the line of a key or sequence entry at indent.
+func writeYAMLValue(w *bufio.Writer, node *yamlNode, indent int) {
+	switch {
+	case node.object && len(node.keys) > 0:
+		w.WriteString("\n")
+		writeYAMLMapping(w, node, indent+2, false)
+	case node.array && len(node.values) > 0:
+		w.WriteString("\n")
+		writeYAMLSequence(w, node, indent+2)
+	default:
+		w.WriteString(" " + inlineYAML(node) + "\n")
+	}
+}
+
+func inlineYAML(node *yamlNode) string {
+	switch {
+	case node.object:
+		return "{}"
+	case node.array:
+		return "[]"
+	}
+	return node.scalar
+}
+
+var (
+	plainYAML = regexp.MustCompile(`^[A-Za-z_$/][A-Za-z0-9_$/ .-]*$`)
+	// reservedYAML are read as booleans or null by YAML 1.1 parsers.
+	reservedYAML = []string{"true", "false", "yes", "no", "on", "off", "y", "n", "null"}
+)
+
+// quoteYAML writes s as a plain scalar when it can't be read as anything but
+// that string, as a double quoted one otherwise. JSON string escapes are
+// valid in YAML double quoted scalars.
+func quoteYAML(s string) string {
+	if plainYAML.MatchString(s) &&
+		!strings.HasSuffix(s, " ") &&
+		!slices.Contains(reservedYAML, strings.ToLower(s)) {
+		return s
+	}
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	_ = encoder.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
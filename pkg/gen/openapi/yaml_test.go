@@ -0,0 +1,172 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWriteYAMLRoundTrip(t *testing.T) {
+	spec := &OpenAPI{
+		OpenAPI: "3.1.0",
+		Info:    Info{Title: `Users: "the" API #1`, Version: "1.0"},
+		Servers: []Server{{URL: "https://example.com/api", Description: "yes"}},
+		Paths: map[string]PathItem{
+			"/users/{id}": {
+				Get: &Operation{
+					Summary:     "multi\nline\ttext ",
+					OperationID: "getUser",
+					Parameters: []Parameter{
+						{Name: "id", In: "path", Required: true, Schema: &Schema{Type: "string", Format: "uuid"}},
+						{Name: "on", In: "query", Schema: &Schema{Type: "boolean"}},
+					},
+					Responses: map[string]Response{
+						"200": {
+							Description: "OK",
+							Content: map[string]MediaType{
+								"application/json": {Schema: &Schema{Ref: "#/components/schemas/User"}},
+							},
+						},
+						"404": {Description: "null"},
+					},
+					Security: []SecurityRequirement{{"bearerAuth": {}}, {"bearerAuth": {"admin", "users:read"}}},
+				},
+			},
+			"/": {},
+		},
+		Components: &Components{
+			Schemas: map[string]*Schema{
+				"User": {
+					Type: "object",
+					Properties: map[string]*Schema{
+						"tags":  {Type: "array", Items: &Schema{Type: "array", Items: &Schema{Type: "string"}}},
+						"12":    {Type: "integer", Format: "int64"},
+						"- odd": {Type: "string"},
+					},
+					Required: []string{"tags", "- odd"},
+				},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := writeYAML(&buf, spec); err != nil {
+		t.Fatalf("writeYAML failed: %v", err)
+	}
+
+	topLevel := make([]string, 0)
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if line != "" && line[0] != ' ' {
+			topLevel = append(topLevel, strings.SplitN(line, ":", 2)[0])
+		}
+	}
+	if want := []string{"openapi", "info", "servers", "paths", "components"}; !reflect.DeepEqual(topLevel, want) {
+		t.Errorf("want top level keys %v, got %v", want, topLevel)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	value, next := parseYAMLBlock(t, lines, 0, 0)
+	if next != len(lines) {
+		t.Fatalf("unparsed YAML from line %d:\n%s", next+1, buf.String())
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		t.Fatalf("marshal parsed YAML failed: %v", err)
+	}
+	var got OpenAPI
+	if err := json.Unmarshal(raw, &got); err != nil {
+		t.Fatalf("unmarshal parsed YAML failed: %v", err)
+	}
+	if !reflect.DeepEqual(&got, spec) {
+		t.Errorf("YAML doesn't round trip, got:\n%s", buf.String())
+	}
+}
+
+// parseYAMLBlock reads the subset of YAML written by writeYAML: block
+// mappings and sequences, plain and double quoted scalars, {} and [].
+func parseYAMLBlock(t *testing.T, lines []string, i, indent int) (any, int) {
+	t.Helper()
+	if strings.HasPrefix(lines[i][indent:], "-") {
+		seq := make([]any, 0)
+		for i < len(lines) && indentOf(lines[i]) == indent && strings.HasPrefix(lines[i][indent:], "-") {
+			rest := lines[i][indent+1:]
+			var item any
+			switch {
+			case rest == "":
+				item, i = parseYAMLBlock(t, lines, i+1, indent+2)
+			case isYAMLKey(t, rest[1:]):
+				// a mapping starting on the line of the "- "
+				lines[i] = strings.Repeat(" ", indent+2) + rest[1:]
+				item, i = parseYAMLBlock(t, lines, i, indent+2)
+			default:
+				item, i = parseYAMLScalar(t, rest[1:]), i+1
+			}
+			seq = append(seq, item)
+		}
+		return seq, i
+	}
+
+	mapping := make(map[string]any)
+	for i < len(lines) && indentOf(lines[i]) == indent {
+		key, rest := splitYAMLKey(t, lines[i][indent:])
+		if rest == "" {
+			mapping[key], i = parseYAMLBlock(t, lines, i+1, indent+2)
+			continue
+		}
+		mapping[key], i = parseYAMLScalar(t, rest[1:]), i+1
+	}
+	return mapping, i
+}
+
+func indentOf(line string) int { return len(line) - len(strings.TrimLeft(line, " ")) }
+
+func isYAMLKey(t *testing.T, s string) bool {
+	t.Helper()
+	if strings.HasPrefix(s, `"`) {
+		_, rest := splitYAMLKey(t, s)
+		return strings.HasPrefix(rest, ":")
+	}
+	return strings.Contains(s, ":")
+}
+
+// splitYAMLKey returns the key of an entry and what follows the colon.
+func splitYAMLKey(t *testing.T, s string) (string, string) {
+	t.Helper()
+	if !strings.HasPrefix(s, `"`) {
+		key, rest, ok := strings.Cut(s, ":")
+		if !ok {
+			t.Fatalf("not a mapping entry: %q", s)
+		}
+		return key, rest
+	}
+	decoder := json.NewDecoder(strings.NewReader(s))
+	var key string
+	if err := decoder.Decode(&key); err != nil {
+		t.Fatalf("bad quoted key %q: %v", s, err)
+	}
+	rest := s[decoder.InputOffset():]
+	if !strings.HasPrefix(rest, ":") {
+		return key, rest
+	}
+	return key, rest[1:]
+}
+
+func parseYAMLScalar(t *testing.T, s string) any {
+	t.Helper()
+	switch s {
+	case "{}":
+		return map[string]any{}
+	case "[]":
+		return []any{}
+	}
+	var v any
+	if err := json.Unmarshal([]byte(s), &v); err == nil {
+		return v
+	}
+	if strings.HasPrefix(s, `"`) {
+		t.Fatalf("bad quoted scalar: %q", s)
+	}
+	return s
+}
@@ -0,0 +1,230 @@
+package generate_test
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/simplicity-load/apispec/pkg/gen"
+	"github.com/simplicity-load/apispec/pkg/gen/testdata/app"
+	"github.com/simplicity-load/apispec/pkg/http"
+	"github.com/simplicity-load/apispec/pkg/parse/server"
+	repr "github.com/simplicity-load/apispec/pkg/repr/http"
+)
+
+// validateStub stands in for github.com/go-playground/validator/v10, with
+// the part of its API generated servers use and its package name.
+const validateStub = `package validator
+
+type Validate struct{}
+
+func New() *Validate { return &Validate{} }
+
+func (*Validate) Struct(any) error { return nil }
+
+type FieldError interface {
+	StructNamespace() string
+	Tag() string
+	Error() string
+}
+
+type ValidationErrors []FieldError
+
+func (ValidationErrors) Error() string { return "validation failed" }
+`
+
+// moduleRequires pins the frameworks generated servers import.
+const moduleRequires = `require (
+	github.com/gin-gonic/gin v1.12.0
+	github.com/go-chi/chi/v5 v5.3.2
+	github.com/gofiber/fiber/v2 v2.52.15
+	github.com/labstack/echo/v4 v4.16.0
+	github.com/simplicity-load/apispec v0.0.0
+)
+`
+
+// runModule writes files to a module replacing apispec with this tree, then
+// vets and tests it. It's skipped when the frameworks can't be downloaded.
+func runModule(t *testing.T, files map[string]string) {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("builds a module")
+	}
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go command not found")
+	}
+	root, err := filepath.Abs("../..")
+	if err != nil {
+		t.Fatalf("resolve module root failed: %v", err)
+	}
+	dir := t.TempDir()
+	files["go.mod"] = "module example.com\n\ngo 1.24\n\n" + moduleRequires +
+		"\nreplace github.com/simplicity-load/apispec => " + root + "\n"
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("create %s failed: %v", name, err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s failed: %v", name, err)
+		}
+	}
+	goCmd := func(args ...string) ([]byte, error) {
+		cmd := exec.Command(goBin, args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOWORK=off")
+		return cmd.CombinedOutput()
+	}
+	if out, err := goCmd("mod", "tidy"); err != nil {
+		t.Skipf("resolving dependencies failed: %v\n%s", err, out)
+	}
+	for _, args := range [][]string{{"vet", "./..."}, {"test", "./..."}} {
+		if out, err := goCmd(args...); err != nil {
+			t.Fatalf("go %s failed: %v\n%s", args[0], err, out)
+		}
+	}
+}
+
+// errorStatusServer tests the server generated in
+// TestGenerateErrorStatusRuntime.
+const errorStatusServer = `package apispec
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	validate "example.com/validate"
+	"github.com/simplicity-load/apispec/pkg/gen/testdata/app"
+)
+
+var statuses = map[error]int{
+	app.ErrNotFound: 404,
+	app.ErrGone:     410,
+	app.ErrExpired:  404,
+}
+
+func TestErrorStatus(t *testing.T) {
+	mux := http.NewServeMux()
+	RegisterHandlers(mux, validate.New(), Options{ErrorStatus: statuses}, app.Handler{})
+	for _, tt := range []struct {
+		method, fail string
+		want         int
+	}{
+		{"GET", "", 204},
+		{"GET", "missing", 404},
+		{"DELETE", "missing", 404},
+		{"GET", "locked", 423},
+		{"DELETE", "locked", 409},
+		{"GET", "gone", 410},
+		{"GET", "expired", 404},
+	} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(tt.method, "/items/1?fail="+tt.fail, nil))
+		if rec.Code != tt.want {
+			t.Errorf("%s with %q answered %d, want %d", tt.method, tt.fail, rec.Code, tt.want)
+		}
+	}
+}
+
+func TestErrorStatusRequiresEverySentinel(t *testing.T) {
+	for _, missing := range []error{app.ErrGone, app.ErrExpired} {
+		partial := make(map[error]int)
+		for err, status := range statuses {
+			if err != missing {
+				partial[err] = status
+			}
+		}
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Error("RegisterHandlers must panic when a sentinel sharing a message isn't mapped")
+				}
+			}()
+			RegisterHandlers(http.NewServeMux(), validate.New(), Options{ErrorStatus: partial}, app.Handler{})
+		}()
+	}
+}
+`
+
+// TestGenerateErrorStatusRuntime runs a server whose endpoints map the same
+// error type to different statuses.
+func TestGenerateErrorStatusRuntime(t *testing.T) {
+	h := app.Handler{}
+	api := http.NewAPI()
+	api.MapErrors(
+		http.ErrorIs(app.ErrNotFound, 404),
+		http.ErrorIs(app.ErrGone, 410),
+		http.ErrorIs(app.ErrExpired, 404),
+	)
+	item := api.Static("items").Param("id")
+	item.Get(h.GetItem, "Get an item", http.Errors(http.ErrorAs[*app.LockedError](423)))
+	item.Delete(h.DeleteItem, "Delete an item", http.Errors(http.ErrorAs[*app.LockedError](409)))
+	paths, err := server.ParsePaths(api)
+	if err != nil {
+		t.Fatalf("ParsePaths failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	err = generate.GenerateNetHTTP(repr.NewRepresentation(paths), &buf, "example.com/validate")
+	if err != nil {
+		t.Fatalf("GenerateNetHTTP failed: %v", err)
+	}
+	runModule(t, map[string]string{
+		"validate/validate.go":  validateStub,
+		"server/apispec.go":     buf.String(),
+		"server/server_test.go": errorStatusServer,
+	})
+}
+
+// cookieServer tests the server generated in TestGenerateCookieRuntime.
+const cookieServer = `package apispec
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	validate "example.com/validate"
+	"github.com/simplicity-load/apispec/pkg/gen/testdata/app"
+)
+
+func TestCookie(t *testing.T) {
+	mux := http.NewServeMux()
+	RegisterHandlers(mux, validate.New(), Options{}, app.Handler{})
+	req := httptest.NewRequest("GET", "/session", nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: "abc"})
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != "session" || cookies[0].Value != "abc" {
+		t.Errorf("response must set the session cookie of the request, got: %v", rec.Header()["Set-Cookie"])
+	}
+}
+`
+
+// TestGenerateCookieRuntime runs a server echoing a request cookie in its
+// response.
+func TestGenerateCookieRuntime(t *testing.T) {
+	h := app.Handler{}
+	api := http.NewAPI()
+	api.Static("session").Get(h.GetSession, "Get the session")
+	paths, err := server.ParsePaths(api)
+	if err != nil {
+		t.Fatalf("ParsePaths failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	err = generate.GenerateNetHTTP(repr.NewRepresentation(paths), &buf, "example.com/validate")
+	if err != nil {
+		t.Fatalf("GenerateNetHTTP failed: %v", err)
+	}
+	runModule(t, map[string]string{
+		"validate/validate.go":  validateStub,
+		"server/apispec.go":     buf.String(),
+		"server/server_test.go": cookieServer,
+	})
+}
@@ -0,0 +1,59 @@
+// Package app holds the handlers tests run generated servers against, it
+// lives in testdata to be importable from the modules they are built in.
+package app
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	ErrNotFound = errors.New("not found")
+	// ErrGone and ErrExpired share a message, generated servers must tell
+	// them apart.
+	ErrGone    = errors.New("gone")
+	ErrExpired = errors.New("gone")
+)
+
+type LockedError struct{}
+
+func (*LockedError) Error() string { return "locked" }
+
+type Item struct {
+	ID   string `json:"id" as:"id,path"`
+	Fail string `json:"-" as:"fail,query"`
+}
+
+// Session echoes the session cookie of the request.
+type Session struct {
+	ID string `json:"-" as:"session,cookie"`
+}
+
+type Handler struct{}
+
+func (Handler) GetSession(ctx context.Context, session *Session) (*Session, error) {
+	return session, nil
+}
+
+func (Handler) GetItem(ctx context.Context, item *Item) (*Item, error) {
+	return item, fail(item.Fail)
+}
+
+func (Handler) DeleteItem(ctx context.Context, item *Item) (*Item, error) {
+	return item, fail(item.Fail)
+}
+
+// fail returns the error named by the fail query parameter.
+func fail(name string) error {
+	switch name {
+	case "missing":
+		return ErrNotFound
+	case "locked":
+		return &LockedError{}
+	case "gone":
+		return ErrGone
+	case "expired":
+		return ErrExpired
+	}
+	return nil
+}
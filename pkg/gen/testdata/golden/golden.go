@@ -0,0 +1,69 @@
+// Package golden holds the handlers the golden files are rendered from, it
+// lives in testdata for the generated code to compile against it.
+package golden
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var ErrNotFound = errors.New("not found")
+
+type ConflictError struct{}
+
+func (*ConflictError) Error() string { return "conflict" }
+
+type Node struct {
+	Name     string `json:"name"`
+	Children []Node `json:"children"`
+}
+
+type User struct {
+	ID      string    `json:"id" as:"id,path" validate:"required"`
+	Name    string    `json:"name" validate:"required"`
+	Created time.Time `json:"created"`
+	Tags    []string  `json:"tags"`
+	Manager *Node     `json:"manager"`
+	Token   string    `json:"-" as:"x-token,header"`
+}
+
+type NewUser struct {
+	Name  string   `json:"name" validate:"required"`
+	Tags  []string `json:"tags"`
+	Token string   `json:"-" as:"x-token,header"`
+}
+
+type List struct {
+	Limit   int    `json:"limit" as:"limit,query"`
+	Query   string `json:"query" as:"q,query"`
+	Session string `json:"-" as:"session,cookie"`
+}
+
+type Users struct {
+	Users  []User `json:"users"`
+	Next   string `json:"-" as:"x-next,header"`
+	Cursor string `json:"-" as:"cursor,cookie"`
+}
+
+type Deleted struct {
+	ID string `json:"id"`
+}
+
+type Handler struct{}
+
+func (Handler) ListUsers(ctx context.Context, param *List) (*Users, error) {
+	return nil, nil
+}
+
+func (Handler) CreateUser(ctx context.Context, param *NewUser) (*User, error) {
+	return nil, nil
+}
+
+func (Handler) GetUser(ctx context.Context, param *User) (*User, error) {
+	return nil, nil
+}
+
+func (Handler) DeleteUser(ctx context.Context, param *User) (*Deleted, error) {
+	return nil, nil
+}
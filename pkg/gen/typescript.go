@@ -0,0 +1,265 @@
+package generate
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	e "github.com/simplicity-load/apispec/pkg/errors"
+	repr "github.com/simplicity-load/apispec/pkg/repr/http"
+)
+
+// clientType is a request or response type with a name unique within the
+// generated client.
+type clientType struct {
+	*repr.Data
+	TypeName string
+}
+
+// clientTypes names every distinct request and response type, as well as
+// every recursive type, see [repr.UniqueTypeNames].
+func clientTypes(endpoints repr.Endpoints) (types []clientType, names map[repr.TypeKey]string) {
+	datas := make(map[repr.TypeKey]*repr.Data)
+	defs := make(map[repr.TypeKey]*repr.StructField)
+	refs := make(map[repr.TypeKey]bool)
+	var collect func(fields []*repr.StructField)
+	collect = func(fields []*repr.StructField) {
+		for _, field := range fields {
+			if key, ok := field.TypeKey(); ok {
+				if field.Ref {
+					refs[key] = true
+				} else {
+					defs[key] = field
+				}
+			}
+			collect(field.SubFields)
+		}
+	}
+	for _, endpoint := range endpoints {
+		for _, data := range []*repr.Data{endpoint.Body, endpoint.Response} {
+			datas[repr.TypeKey{Import: data.Import, Name: data.Name}] = data
+			collect(data.Fields)
+		}
+	}
+	for key := range refs {
+		if _, ok := datas[key]; !ok {
+			datas[key] = &repr.Data{Name: key.Name, Import: key.Import, Fields: defs[key].SubFields}
+		}
+	}
+
+	keys := slices.Collect(maps.Keys(datas))
+	names = repr.UniqueTypeNames(keys)
+	slices.SortFunc(keys, func(a, b repr.TypeKey) int {
+		return strings.Compare(names[a], names[b])
+	})
+
+	types = make([]clientType, len(keys))
+	for i, key := range keys {
+		types[i] = clientType{Data: datas[key], TypeName: names[key]}
+	}
+	return types, names
+}
+
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToLower(s[:1]) + s[1:]
+}
+
+// tsReserved lists the reserved words of TypeScript and JavaScript, strict
+// mode included, along with the helpers the client declares.
+var tsReserved = []string{
+	"await", "break", "case", "catch", "class", "const", "continue",
+	"debugger", "default", "delete", "do", "else", "enum", "export",
+	"extends", "false", "finally", "for", "function", "if", "implements",
+	"import", "in", "instanceof", "interface", "let", "new", "null",
+	"package", "private", "protected", "public", "return", "static",
+	"super", "switch", "this", "throw", "true", "try", "typeof", "var",
+	"void", "while", "with", "yield",
+	"request", "setParam", "readParam", "omit",
+}
+
+// tsFuncName renders name as the identifier of a client function, reserved
+// words get a "_" suffix.
+func tsFuncName(name string) string {
+	name = lowerFirst(name)
+	if slices.Contains(tsReserved, name) {
+		return name + "_"
+	}
+	return name
+}
+
+// fieldName is the name a field is serialized as.
+func fieldName(field *repr.StructField) string {
+	if field.Serialization != nil {
+		return field.Serialization.Name
+	}
+	return field.Name
+}
+
+// tsProp renders name as a TypeScript property key, quoting it when it isn't
+// a valid identifier.
+func tsProp(name string) string {
+	for i, r := range name {
+		if r == '_' || r == '$' || unicode.IsLetter(r) || (i > 0 && unicode.IsDigit(r)) {
+			continue
+		}
+		return strconv.Quote(name)
+	}
+	return name
+}
+
+// tsAccess renders the access of property name on ident.
+func tsAccess(ident, name string) string {
+	prop := tsProp(name)
+	if strings.HasPrefix(prop, `"`) {
+		return ident + "[" + prop + "]"
+	}
+	return ident + "." + prop
+}
+
+// tsFields lists the fields of a TypeScript type, the ones left out of JSON
+// with json:"-" aren't sent or read.
+func tsFields(fields []*repr.StructField) []*repr.StructField {
+	return slices.DeleteFunc(slices.Clone(fields), func(field *repr.StructField) bool {
+		return field.Serialization != nil && field.Serialization.Name == "-"
+	})
+}
+
+// tsOptional marks the properties of pointer fields optional, they may be
+// left out of requests.
+func tsOptional(field *repr.StructField) string {
+	if field.Pointer {
+		return "?"
+	}
+	return ""
+}
+
+// tsTyper returns tsType, rendering the TypeScript type of a field. Structs
+// are referred to by their name in named, the interfaces the client emits,
+// and inlined otherwise. Pointers may be null and durations sent outside of
+// the JSON body are written like "1h30m".
+func tsTyper(named map[repr.TypeKey]string) func(*repr.StructField) string {
+	var tsType, tsValueType func(field *repr.StructField) string
+	tsObject := func(fields []*repr.StructField) string {
+		fields = tsFields(fields)
+		if len(fields) == 0 {
+			return "Record<string, never>"
+		}
+		props := make([]string, len(fields))
+		for i, field := range fields {
+			props[i] = fmt.Sprintf("%s%s: %s", tsProp(fieldName(field)), tsOptional(field), tsType(field))
+		}
+		return "{ " + strings.Join(props, "; ") + " }"
+	}
+	tsType = func(field *repr.StructField) string {
+		if field.Pointer {
+			return tsValueType(field) + " | null"
+		}
+		return tsValueType(field)
+	}
+	tsValueType = func(field *repr.StructField) string {
+		if field.Format == "duration" && field.Serialization != nil &&
+			field.Serialization.Type != repr.SerializationJSON {
+			return "string"
+		}
+		switch field.Type {
+		case reflect.String:
+			return "string"
+		case reflect.Bool:
+			return "boolean"
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return "number"
+		case reflect.Array, reflect.Slice:
+			if len(field.SubFields) == 0 {
+				return "unknown[]"
+			}
+			return "Array<" + tsType(field.SubFields[0]) + ">"
+		case reflect.Map:
+			if len(field.SubFields) < 2 {
+				return "Record<string, unknown>"
+			}
+			return "Record<" + tsType(field.SubFields[0]) + ", " + tsType(field.SubFields[1]) + ">"
+		case reflect.Struct:
+			if key, ok := field.TypeKey(); ok {
+				if name, ok := named[key]; ok {
+					return name
+				}
+			}
+			return tsObject(field.SubFields)
+		default:
+			return "unknown"
+		}
+	}
+	return tsType
+}
+
+// tsPath renders the path of endpoint as a template literal, substituting
+// parameters with the path bound property of req they name.
+func tsPath(endpoint *repr.Endpoint) (string, error) {
+	url := strings.Builder{}
+	url.WriteRune('`')
+	for path := range repr.PathStrings(endpoint.Path).NoRootPaths() {
+		url.WriteRune('/')
+		switch path.Type {
+		case repr.PathSTATIC:
+			url.WriteString(path.Name)
+		case repr.PathPARAM:
+			field := pathField(endpoint.Body, path.Name)
+			if field == nil {
+				return "", e.ErrFailedActionWithItem("find path field", path.Name, e.ErrNoValue)
+			}
+			fmt.Fprintf(&url, "${encodeURIComponent(String(%s))}", tsAccess("req", fieldName(field)))
+		case repr.PathWILDCARD:
+			field := pathField(endpoint.Body, path.Name)
+			if field == nil {
+				return "", e.ErrFailedActionWithItem("find path field", path.Name, e.ErrNoValue)
+			}
+			// the segments of a wildcard are escaped one by one
+			fmt.Fprintf(&url, "${encodeURIComponent(%s).replace(/%%2F/g, \"/\")}", tsAccess("req", fieldName(field)))
+		default:
+			return "", e.ErrBadValueFromList("path type", path.Type, repr.ValidPathTypes)
+		}
+	}
+	if url.Len() == 1 {
+		url.WriteRune('/')
+	}
+	url.WriteRune('`')
+	return url.String(), nil
+}
+
+// tsParse renders the conversion of the string value to the TypeScript
+// type of field, for fields read from response headers.
+func tsParse(field *repr.StructField, value string) string {
+	if field.Format != "" {
+		return value
+	}
+	switch field.Type {
+	case reflect.Bool:
+		return value + ` === "true"`
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "Number(" + value + ")"
+	default:
+		return value
+	}
+}
+
+// paramNames lists the serialization names of the fields of data that are
+// sent outside of the JSON body.
+func paramNames(data *repr.Data) []string {
+	names := make([]string, 0)
+	for _, field := range data.Fields {
+		if field.Serialization != nil &&
+			slices.Contains(repr.ApiSpecSerializationTypes, field.Serialization.Type) {
+			names = append(names, strconv.Quote(field.Serialization.Name))
+		}
+	}
+	return names
+}
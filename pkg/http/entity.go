@@ -1,37 +1,96 @@
 package http
 
-import "io"
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"reflect"
+	"runtime"
+	"slices"
+)
 
 type Method string
 
 const (
-	POST   Method = "POST"
-	GET    Method = "GET"
-	PUT    Method = "PUT"
-	PATCH  Method = "PATCH"
-	DELETE Method = "DELETE"
+	POST    Method = "POST"
+	GET     Method = "GET"
+	PUT     Method = "PUT"
+	PATCH   Method = "PATCH"
+	DELETE  Method = "DELETE"
+	HEAD    Method = "HEAD"
+	OPTIONS Method = "OPTIONS"
+	TRACE   Method = "TRACE"
 )
 
+// Methods lists the standard methods in the order endpoints are visited,
+// others can be declared with [Path.Handle].
+var Methods = []Method{GET, POST, PUT, PATCH, DELETE, HEAD, OPTIONS, TRACE}
+
 type Endpoint struct {
 	Handler     any
 	Description string
 	Authz       []string
 	Middleware  []any
+	Errors      []ErrorStatus
+	// Tags group the endpoint in the OpenAPI document, after the tags of its
+	// paths.
+	Tags        []string
+	OperationID string
+	Summary     string
+	Deprecated  bool
+	// Status is the status successful responses are answered with, 0 for
+	// the default, see [Status].
+	Status int
+	// AutoHead answers HEAD requests with the handler of a GET endpoint, see
+	// [AutoHead].
+	AutoHead bool
+	// Source is the file:line the endpoint was declared at.
+	Source string
 }
 
 type Endpoints map[Method]Endpoint
 
+// Methods lists the methods of eps in [Methods] order, followed by any other
+// method in lexical order, so they're visited the same way on every run.
+func (eps Endpoints) Methods() []Method {
+	methods := make([]Method, 0, len(eps))
+	for _, method := range Methods {
+		if _, ok := eps[method]; ok {
+			methods = append(methods, method)
+		}
+	}
+	others := make([]Method, 0)
+	for method := range eps {
+		if !slices.Contains(Methods, method) {
+			others = append(others, method)
+		}
+	}
+	slices.Sort(others)
+	return append(methods, others...)
+}
+
 type optionType int
 
 const (
 	optAuthz optionType = iota
 	optMiddleware
+	optErrors
+	optTags
+	optOperationID
+	optSummary
+	optDeprecated
+	optStatus
+	optAutoHead
 )
 
 type EndpointOpt struct {
 	typ    optionType
 	authz  []string
 	middle []any
+	errs   []ErrorStatus
+	tags   []string
+	text   string
+	status int
 }
 
 func (o EndpointOpt) getOptionType() optionType { return o.typ }
@@ -44,12 +103,78 @@ func Middleware(values ...any) EndpointOpt {
 	return EndpointOpt{typ: optMiddleware, middle: values}
 }
 
+// Errors maps error types returned by the handler to HTTP status codes, they
+// take precedence over the ones mapped on its paths.
+func Errors(values ...ErrorStatus) EndpointOpt {
+	return EndpointOpt{typ: optErrors, errs: values}
+}
+
+// Tags groups the endpoint in the OpenAPI document, after the tags of its
+// paths.
+func Tags(values ...string) EndpointOpt {
+	return EndpointOpt{typ: optTags, tags: values}
+}
+
+// OperationID identifies the endpoint in the OpenAPI document, it must be
+// unique. Defaults to the handler's name.
+func OperationID(value string) EndpointOpt {
+	return EndpointOpt{typ: optOperationID, text: value}
+}
+
+// Summary is the short summary of the endpoint in the OpenAPI document, the
+// description is documented in full. Defaults to the handler's name.
+func Summary(value string) EndpointOpt {
+	return EndpointOpt{typ: optSummary, text: value}
+}
+
+// Deprecated marks the endpoint as deprecated in the OpenAPI document.
+func Deprecated() EndpointOpt {
+	return EndpointOpt{typ: optDeprecated}
+}
+
+// Status answers successful responses with code, a 2xx status. Defaults to
+// 200, or to 204 when the response has no JSON fields; 204 and 205 responses
+// have no body.
+func Status(code int) EndpointOpt {
+	return EndpointOpt{typ: optStatus, status: code}
+}
+
+// AutoHead answers HEAD requests with the handler of the GET endpoint it's
+// declared on, with the headers and status of its responses but no body. A
+// HEAD endpoint declared on the same path takes precedence. ServeMux answers
+// HEAD requests with GET handlers either way.
+func AutoHead() EndpointOpt {
+	return EndpointOpt{typ: optAutoHead}
+}
+
+// ErrorStatus maps errors returned by handlers to an HTTP status code.
+type ErrorStatus struct {
+	// Err is matched with errors.Is, nil when matching by Type.
+	Err error
+	// Type is matched with errors.As, nil when matching by Err.
+	Type   reflect.Type
+	Status int
+}
+
+// ErrorIs maps errors matching err with [errors.Is] to status. Generated
+// servers match sentinel errors against a single table, so they are only
+// mapped on the root path.
+func ErrorIs(err error, status int) ErrorStatus {
+	return ErrorStatus{Err: err, Status: status}
+}
+
+// ErrorAs maps errors matching T with [errors.As] to status.
+func ErrorAs[T error](status int) ErrorStatus {
+	return ErrorStatus{Type: reflect.TypeFor[T](), Status: status}
+}
+
 type PathType int
 
 const (
 	PathRoot PathType = iota
 	PathStatic
 	PathParam
+	PathWildcard
 )
 
 type Path struct {
@@ -57,39 +182,130 @@ type Path struct {
 	Type       PathType
 	Endpoints  map[Method]Endpoint
 	Middleware []any
-	SubPaths   []*Path
+	Errors     []ErrorStatus
+	// Tags are inherited by the endpoints of the path and its subpaths.
+	Tags     []string
+	SubPaths []*Path
+	// Redefined lists the endpoints replaced by a later declaration of the
+	// same method, they're reported by the parser.
+	Redefined []Redefinition
+	// Source is the file:line the path was declared at.
+	Source string
+}
+
+// Redefinition is an endpoint replaced by another declaration of Method.
+type Redefinition struct {
+	Method   Method
+	Endpoint Endpoint
 }
 
 func NewAPI() *Path {
-	return &Path{Type: PathRoot, Endpoints: make(map[Method]Endpoint)}
+	return &Path{Type: PathRoot, Endpoints: make(map[Method]Endpoint), Source: caller(1)}
 }
 
 func (p *Path) Static(name string) *Path {
-	child := &Path{Name: name, Type: PathStatic, Endpoints: make(map[Method]Endpoint)}
+	child := &Path{Name: name, Type: PathStatic, Endpoints: make(map[Method]Endpoint), Source: caller(1)}
 	p.SubPaths = append(p.SubPaths, child)
 	return child
 }
 
 func (p *Path) Param(name string) *Path {
-	child := &Path{Name: name, Type: PathParam, Endpoints: make(map[Method]Endpoint)}
+	child := &Path{Name: name, Type: PathParam, Endpoints: make(map[Method]Endpoint), Source: caller(1)}
 	p.SubPaths = append(p.SubPaths, child)
 	return child
 }
 
+// Wildcard matches the rest of the URL, slashes included, and binds it to
+// the path field called name, which must be a string. It must be the last
+// segment of the route, no path can be declared under it.
+func (p *Path) Wildcard(name string) *Path {
+	child := &Path{Name: name, Type: PathWildcard, Endpoints: make(map[Method]Endpoint), Source: caller(1)}
+	p.SubPaths = append(p.SubPaths, child)
+	return child
+}
+
+// caller returns the file:line of the caller skip frames above its own
+// caller.
+func caller(skip int) string {
+	_, file, line, ok := runtime.Caller(skip + 1)
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("%s:%d", file, line)
+}
+
 func (p *Path) Use(middleware ...any) {
 	p.Middleware = append(p.Middleware, middleware...)
 }
 
+// Tag groups the endpoints of p and its subpaths in the OpenAPI document.
+func (p *Path) Tag(tags ...string) {
+	p.Tags = append(p.Tags, tags...)
+}
+
+// MapErrors maps errors returned by the handlers of p and its subpaths to
+// HTTP status codes. Error types mapped on subpaths take precedence, sentinel
+// errors are only mapped on the root.
+func (p *Path) MapErrors(statuses ...ErrorStatus) {
+	p.Errors = append(p.Errors, statuses...)
+}
+
+// ErrorStatusMap collects the sentinel errors mapped on p, for the generated
+// server to match handler errors against.
+func (p *Path) ErrorStatusMap() map[error]int {
+	statuses := make(map[error]int)
+	p.errorStatusMap(statuses)
+	return statuses
+}
+
+func (p *Path) errorStatusMap(statuses map[error]int) {
+	add := func(errs []ErrorStatus) {
+		for _, es := range errs {
+			if es.Err == nil {
+				continue
+			}
+			if _, ok := statuses[es.Err]; !ok {
+				statuses[es.Err] = es.Status
+			}
+		}
+	}
+	add(p.Errors)
+	for _, method := range Endpoints(p.Endpoints).Methods() {
+		add(p.Endpoints[method].Errors)
+	}
+	for _, sub := range p.SubPaths {
+		sub.errorStatusMap(statuses)
+	}
+}
+
 func (p *Path) addEndpoint(method Method, handler any, desc string, opts []EndpointOpt) {
-	ep := Endpoint{Handler: handler, Description: desc}
+	// called by the method helpers, the declaration is two frames up
+	ep := Endpoint{Handler: handler, Description: desc, Source: caller(2)}
 	for _, opt := range opts {
 		switch opt.getOptionType() {
 		case optAuthz:
 			ep.Authz = opt.authz
 		case optMiddleware:
 			ep.Middleware = opt.middle
+		case optErrors:
+			ep.Errors = opt.errs
+		case optTags:
+			ep.Tags = append(ep.Tags, opt.tags...)
+		case optOperationID:
+			ep.OperationID = opt.text
+		case optSummary:
+			ep.Summary = opt.text
+		case optDeprecated:
+			ep.Deprecated = true
+		case optStatus:
+			ep.Status = opt.status
+		case optAutoHead:
+			ep.AutoHead = true
 		}
 	}
+	if old, ok := p.Endpoints[method]; ok {
+		p.Redefined = append(p.Redefined, Redefinition{Method: method, Endpoint: old})
+	}
 	p.Endpoints[method] = ep
 }
 
@@ -113,18 +329,59 @@ func (p *Path) Delete(handler any, desc string, opts ...EndpointOpt) {
 	p.addEndpoint(DELETE, handler, desc, opts)
 }
 
+func (p *Path) Head(handler any, desc string, opts ...EndpointOpt) {
+	p.addEndpoint(HEAD, handler, desc, opts)
+}
+
+// Options declares the OPTIONS endpoint of the path, replacing the one
+// generated servers answer with the methods of the path.
+func (p *Path) Options(handler any, desc string, opts ...EndpointOpt) {
+	p.addEndpoint(OPTIONS, handler, desc, opts)
+}
+
+// Handle declares an endpoint of any method, e.g. TRACE or a custom one like
+// "PURGE". Methods are upper case tokens.
+func (p *Path) Handle(method Method, handler any, desc string, opts ...EndpointOpt) {
+	p.addEndpoint(method, handler, desc, opts)
+}
+
 type HttpServer struct {
+	// ServerTemplate selects the generated server, either by the name of a
+	// registered backend ("fiber", "nethttp", "chi", "echo", "gin") or by the
+	// path of a custom template extending the "fiber" backend. Defaults to
+	// "fiber".
 	ServerTemplate string
+	// ServerTemplateFS is where a custom ServerTemplate is read from, the
+	// OS filesystem when nil.
+	ServerTemplateFS fs.FS
+	// ClientTemplate selects the generated client, either by the name of a
+	// registered client ("go", "typescript") or by the path of a custom
+	// template, read from ServerTemplateFS when set. Defaults to "go".
 	ClientTemplate string
 	Routes         *Path
 	OutputFile     io.Writer
 	ValidateUrl    string
+	// ClientOutputFile receives the client, none is generated when nil.
+	ClientOutputFile io.Writer
+	// ClientPackage is the package name of the client. Defaults to "client".
+	ClientPackage string
 }
 
+type OpenAPIFormat string
+
+const (
+	OpenAPIJSON OpenAPIFormat = "json"
+	OpenAPIYAML OpenAPIFormat = "yaml"
+)
+
+var OpenAPIFormats = []OpenAPIFormat{OpenAPIJSON, OpenAPIYAML}
+
 type OpenAPIConfig struct {
 	Routes     *Path
 	OutputFile io.Writer
 	Title      string
 	Version    string
 	ServerURL  string
+	// Format of the document. Defaults to JSON.
+	Format OpenAPIFormat
 }
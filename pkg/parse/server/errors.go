@@ -5,6 +5,9 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"strings"
+
+	"github.com/simplicity-load/apispec/pkg/http"
 )
 
 // *-------------*
@@ -28,11 +31,173 @@ func ErrFnSignature(got any, err ...error) error {
 	return errors.New(signature.String())
 }
 
+// RouteError is a problem with the definition of an endpoint, or of a path
+// when Method is empty.
+type RouteError struct {
+	// URL is the full path, with parameters as {name}.
+	URL     string
+	Method  http.Method
+	Handler string
+	// Field is the path of the offending field, e.g. "body.Address.Zip".
+	Field string
+	// Source is the file:line of the offending declaration, if known.
+	Source string
+	Err    error
+}
+
+func (re *RouteError) Error() string {
+	location := re.URL
+	if re.Method != "" {
+		location = string(re.Method) + " " + location
+	}
+	if re.Field != "" {
+		location += " " + re.Field
+	}
+	if re.Handler != "" {
+		location += " (" + re.Handler + ")"
+	}
+	if re.Source != "" {
+		location = re.Source + ": " + location
+	}
+	return location + ": " + re.Err.Error()
+}
+
+func (re *RouteError) Unwrap() error { return re.Err }
+
+// RouteErrors lists every problem found in a route tree, in declaration
+// order. Unwrap returns them for errors.Join, errors.Is and errors.As.
+type RouteErrors []*RouteError
+
+func (re RouteErrors) Error() string {
+	msgs := make([]string, len(re))
+	for i, err := range re {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+func (re RouteErrors) Unwrap() []error {
+	errs := make([]error, len(re))
+	for i, err := range re {
+		errs[i] = err
+	}
+	return errs
+}
+
+// fieldError is a problem with the field at path of a request or response.
+type fieldError struct {
+	path []string
+	err  error
+}
+
+type fieldErrors []*fieldError
+
+func (fe fieldErrors) Error() string {
+	msgs := make([]string, len(fe))
+	for i, err := range fe {
+		msgs[i] = strings.Join(err.path, ".") + ": " + err.err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// fieldErrorsOf prefixes the paths of the field errors in err with name,
+// any other error is located at name itself.
+func fieldErrorsOf(name string, err error) fieldErrors {
+	if err == nil {
+		return nil
+	}
+	var fields fieldErrors
+	if !errors.As(err, &fields) {
+		return fieldErrors{{path: []string{name}, err: err}}
+	}
+	prefixed := make(fieldErrors, len(fields))
+	for i, fe := range fields {
+		prefixed[i] = &fieldError{path: append([]string{name}, fe.path...), err: fe.err}
+	}
+	return prefixed
+}
+
 func ErrBodyTypeError(got any, err error) error {
 	// TODO
 	return nil
 }
 
+func ErrBadStatus(got int) error {
+	return fmt.Errorf(`invalid error status code: %d, required: 400 to 599`, got)
+}
+
+func ErrWildcardNotLast(name string) error {
+	return fmt.Errorf(`paths declared under wildcard %q, required: wildcards last`, name)
+}
+
+func ErrWildcardFieldType(name string, got reflect.Kind) error {
+	return fmt.Errorf(`wildcard %q bound to a %s field, required: string`, name, got)
+}
+
+func ErrBadMethod(method http.Method) error {
+	return fmt.Errorf(`invalid method: %q, required: upper case letters`, method)
+}
+
+func ErrAutoHeadMethod(method http.Method) error {
+	return fmt.Errorf(`AutoHead declared on %s, required: GET`, method)
+}
+
+func ErrBadSuccessStatus(got int) error {
+	return fmt.Errorf(`invalid status code: %d, required: 200 to 299`, got)
+}
+
+func ErrStatusWithBody(status int) error {
+	return fmt.Errorf(`status %d has no body, the response has JSON fields`, status)
+}
+
+func ErrConflictingErrorStatus(err error, got, want int) error {
+	return fmt.Errorf(`error "%s" mapped to both %d and %d`, err, got, want)
+}
+
+func ErrSentinelBelowRoot(err error) error {
+	return fmt.Errorf(`error "%s" mapped with ErrorIs below the root, required: MapErrors on the root or ErrorAs`, err)
+}
+
+func ErrPathParamMissing(param string) error {
+	return fmt.Errorf(`route parameter %q isn't bound by a path field, required: as:"%s,path"`, param, param)
+}
+
+func ErrPathFieldUnknown(name string) error {
+	return fmt.Errorf(`path field %q matches no route parameter`, name)
+}
+
+func ErrPathFieldMisnamed(name, param string) error {
+	return fmt.Errorf(`path field %q matches no route parameter, did you mean %q`, name, param)
+}
+
+func ErrDuplicateRoute(source string) error {
+	return fmt.Errorf(`route already declared at %s`, source)
+}
+
+func ErrShadowedRoute(url, source string) error {
+	return fmt.Errorf(`route is shadowed by %s declared earlier at %s, declare it first`, url, source)
+}
+
+func ErrAmbiguousRoute(url, source string) error {
+	return fmt.Errorf(`route is ambiguous with %s declared at %s, neither is more specific`, url, source)
+}
+
+func ErrDuplicateOperationID(id, source string) error {
+	return fmt.Errorf(`operation ID %q already declared at %s`, id, source)
+}
+
+func ErrParamNameConflict(got, want, source string) error {
+	return fmt.Errorf(`route parameter %q conflicts with %q declared at the same position at %s`, got, want, source)
+}
+
+func ErrBadValidation(rule, reason string) error {
+	return fmt.Errorf(`invalid validation %q: %s`, rule, reason)
+}
+
+func ErrUnknownValidation(name string) error {
+	return fmt.Errorf(`unknown validation rule %q, custom rules must be registered with RegisterValidation`, name)
+}
+
 func ErrBadOnlyLowerFormatting(got string) error {
 	return errBadFormatting(got, "lowercase a to z")
 }
@@ -48,6 +213,8 @@ var (
 	ErrFnIsAnon              = errors.New("function must not be anonymous")
 	ErrSinglePointerRequired = errors.New("single pointer required")
 	ErrNoFloat               = errors.New("floats aren't allowed")
+	ErrErrorTypeIsAnon       = errors.New("error type must be named")
+	ErrErrorNotComparable    = errors.New("error must be comparable")
 )
 
 // *-----------------*
@@ -1,9 +1,13 @@
 package server
 
 import (
+	"encoding"
+	"encoding/json"
 	"reflect"
 	"runtime"
 	"slices"
+	"strings"
+	"time"
 
 	e "github.com/simplicity-load/apispec/pkg/errors"
 )
@@ -36,6 +40,37 @@ var allowedMapKeyTypes = slices.Concat(intTypes, []reflect.Kind{
 	reflect.String,
 })
 
+var (
+	timeType     = reflect.TypeFor[time.Time]()
+	durationType = reflect.TypeFor[time.Duration]()
+
+	textMarshalerInterface = reflect.TypeFor[encoding.TextMarshaler]()
+	jsonMarshalerInterface = reflect.TypeFor[json.Marshaler]()
+)
+
+// scalarFormat reports whether s is serialized as a single value instead of
+// by its kind, along with a hint on the format of the value.
+func scalarFormat(s reflect.Type) (string, bool) {
+	switch {
+	case s == timeType:
+		return "date-time", true
+	case s == durationType:
+		return "duration", true
+	case implements(s, textMarshalerInterface), implements(s, jsonMarshalerInterface):
+		if strings.EqualFold(s.Name(), "uuid") {
+			return "uuid", true
+		}
+		return "", true
+	default:
+		return "", false
+	}
+}
+
+// implements reports whether s or a pointer to it implements i.
+func implements(s, i reflect.Type) bool {
+	return s.Implements(i) || reflect.PointerTo(s).Implements(i)
+}
+
 type qualifiedFnName string
 
 func getFnName(fn any) (qualifiedFnName, error) {
@@ -58,6 +93,8 @@ func extractFieldTypeIter(s reflect.Type, indirectionLevel int) (reflect.Type, e
 	switch {
 	case s.Kind() == reflect.Pointer:
 		return extractFieldTypeIter(s.Elem(), indirectionLevel+1)
+	case isScalar(s):
+		return s, nil
 	case s.Kind() == reflect.Float32, s.Kind() == reflect.Float64:
 		return nil, ErrNoFloat
 	case slices.Contains(allowedFieldTypes, s.Kind()):
@@ -67,6 +104,11 @@ func extractFieldTypeIter(s reflect.Type, indirectionLevel int) (reflect.Type, e
 	}
 }
 
+func isScalar(s reflect.Type) bool {
+	_, ok := scalarFormat(s)
+	return ok
+}
+
 func typeNameFromList[T any](s []T) []string {
 	result := make([]string, 0, len(s))
 	for _, x := range s {
@@ -0,0 +1,147 @@
+package server
+
+import (
+	"slices"
+
+	"github.com/simplicity-load/apispec/pkg/http"
+	repr "github.com/simplicity-load/apispec/pkg/repr/http"
+)
+
+// redefinitionErrors reports the endpoints of route replaced by a later
+// declaration of the same method.
+func redefinitionErrors(route *http.Path, paths repr.PathStrings) RouteErrors {
+	errs := make(RouteErrors, 0)
+	for _, redefined := range route.Redefined {
+		ep := route.Endpoints[redefined.Method]
+		handler := ""
+		if name, err := getFnName(ep.Handler); err == nil {
+			handler = string(name)
+		}
+		errs = append(errs, &RouteError{
+			URL:     routeURL(paths),
+			Method:  redefined.Method,
+			Handler: handler,
+			Source:  ep.Source,
+			Err:     ErrDuplicateRoute(redefined.Endpoint.Source),
+		})
+	}
+	return errs
+}
+
+// checkParamNames makes sure parameters at the same position of the route
+// tree share a name, whichever path they're declared on. params holds the
+// first parameter seen after each prefix, with parameters written as {}.
+func checkParamNames(route *http.Path, prefix string, params map[string]*http.Path) RouteErrors {
+	errs := make(RouteErrors, 0)
+	switch route.Type {
+	case http.PathStatic:
+		prefix += "/" + route.Name
+	case http.PathParam, http.PathWildcard:
+		first, ok := params[prefix]
+		if !ok {
+			params[prefix] = route
+		} else if first.Name != route.Name {
+			errs = append(errs, &RouteError{
+				URL:    prefix + "/{" + route.Name + "}",
+				Source: route.Source,
+				Err:    ErrParamNameConflict(route.Name, first.Name, first.Source),
+			})
+		}
+		prefix += "/{}"
+	}
+	for _, sub := range route.SubPaths {
+		errs = append(errs, checkParamNames(sub, prefix, params)...)
+	}
+	return errs
+}
+
+// checkRoutes reports every endpoint that matches the same requests as one
+// declared before it. Backends matching in registration order never reach a
+// route declared after a more general one, and ServeMux refuses routes where
+// neither is more specific.
+func checkRoutes(path *repr.Path) RouteErrors {
+	errs := make(RouteErrors, 0)
+	endpoints := slices.Collect(path.AllEndpoints())
+	for i, endpoint := range endpoints {
+		for _, earlier := range endpoints[:i] {
+			if earlier.Method != endpoint.Method {
+				continue
+			}
+			earlierGeneral, general, ok := compareRoutes(earlier.Path, endpoint.Path)
+			if !ok {
+				continue
+			}
+			var err error
+			switch {
+			case !earlierGeneral && !general:
+				err = ErrDuplicateRoute(earlier.Source)
+			case earlierGeneral && !general:
+				err = ErrShadowedRoute(routeURL(earlier.Path), earlier.Source)
+			case earlierGeneral && general:
+				err = ErrAmbiguousRoute(routeURL(earlier.Path), earlier.Source)
+			default:
+				// the more specific route is registered first
+				continue
+			}
+			errs = append(errs, &RouteError{
+				URL:     routeURL(endpoint.Path),
+				Method:  endpoint.Method,
+				Handler: handlerName(endpoint.Handler),
+				Source:  endpoint.Source,
+				Err:     err,
+			})
+			break
+		}
+	}
+	return errs
+}
+
+// compareRoutes reports whether some request matches both a and b, and
+// whether each has a parameter where the other has a static segment. A
+// wildcard matches the segment it's declared at and any following one.
+func compareRoutes(a, b repr.PathStrings) (aGeneral, bGeneral, overlap bool) {
+	as := slices.Collect(a.NoRootPaths())
+	bs := slices.Collect(b.NoRootPaths())
+	for i := range max(len(as), len(bs)) {
+		if i == len(as) || i == len(bs) {
+			return false, false, false
+		}
+		aWildcard, bWildcard := as[i].Type == repr.PathWILDCARD, bs[i].Type == repr.PathWILDCARD
+		if aWildcard || bWildcard {
+			return aGeneral || aWildcard && !bWildcard, bGeneral || bWildcard && !aWildcard, true
+		}
+		aParam, bParam := as[i].Type == repr.PathPARAM, bs[i].Type == repr.PathPARAM
+		switch {
+		case aParam && !bParam:
+			aGeneral = true
+		case !aParam && bParam:
+			bGeneral = true
+		case !aParam && !bParam && as[i].Name != bs[i].Name:
+			return false, false, false
+		}
+	}
+	return aGeneral, bGeneral, true
+}
+
+// checkOperationIDs makes sure the operation IDs set on endpoints are unique.
+func checkOperationIDs(path *repr.Path) RouteErrors {
+	errs := make(RouteErrors, 0)
+	declared := make(map[string]*repr.Endpoint)
+	for endpoint := range path.AllEndpoints() {
+		if endpoint.OperationID == "" {
+			continue
+		}
+		if earlier, ok := declared[endpoint.OperationID]; ok {
+			errs = append(errs, &RouteError{
+				URL:     routeURL(endpoint.Path),
+				Method:  endpoint.Method,
+				Handler: handlerName(endpoint.Handler),
+				Source:  endpoint.Source,
+				Err:     ErrDuplicateOperationID(endpoint.OperationID, earlier.Source),
+			})
+			continue
+		}
+		declared[endpoint.OperationID] = endpoint
+	}
+	return errs
+}
@@ -1,9 +1,11 @@
 package server
 
 import (
+	"cmp"
 	"context"
 	"errors"
 	"iter"
+	"maps"
 	"reflect"
 	"slices"
 	"strings"
@@ -13,24 +15,93 @@ import (
 	repr "github.com/simplicity-load/apispec/pkg/repr/http"
 )
 
+// ParsePaths walks the entire route tree, on failure the error is a
+// [RouteErrors] listing every problem found.
 func ParsePaths(config *http.Path) (*repr.Path, error) {
-	return traversePathsIter(config, repr.PathStrings{}, repr.Middlewares{})
+	path, errs := traversePathsIter(config, repr.PathStrings{}, repr.Middlewares{}, nil, nil)
+	errs = append(errs, checkParamNames(config, "", make(map[string]*http.Path))...)
+	errs = append(errs, checkRoutes(path)...)
+	errs = append(errs, checkOperationIDs(path)...)
+	errs = append(errs, checkErrorStatuses(path)...)
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return path, nil
 }
 
-func traversePathsIter(route *http.Path, paths []*repr.PathString, parentMiddleware repr.Middlewares) (*repr.Path, error) {
+// checkErrorStatuses makes sure every sentinel error is mapped to a single
+// status, generated servers match them against one table.
+func checkErrorStatuses(path *repr.Path) RouteErrors {
+	errs := make(RouteErrors, 0)
+	statuses := make(map[error]int)
+	for endpoint := range path.AllEndpoints() {
+		for _, es := range endpoint.Errors {
+			if es.Err == nil {
+				continue
+			}
+			status, ok := statuses[es.Err]
+			if ok && status != es.Status {
+				errs = append(errs, &RouteError{
+					URL:     routeURL(endpoint.Path),
+					Method:  endpoint.Method,
+					Handler: handlerName(endpoint.Handler),
+					Source:  endpoint.Source,
+					Err:     ErrConflictingErrorStatus(es.Err, status, es.Status),
+				})
+				continue
+			}
+			statuses[es.Err] = es.Status
+		}
+	}
+	return errs
+}
+
+func traversePathsIter(
+	route *http.Path,
+	paths []*repr.PathString,
+	parentMiddleware repr.Middlewares,
+	parentErrors []*repr.ErrorStatus,
+	parentTags []string,
+) (*repr.Path, RouteErrors) {
 	ps := parsePathString(route)
 	pathStrings := make([]*repr.PathString, 0, len(paths)+1)
 	pathStrings = append(pathStrings, paths...)
 	pathStrings = append(pathStrings, ps)
 
-	endpoints, err := parseEndpoints(route.Endpoints, pathStrings)
+	errs := make(RouteErrors, 0)
+	pathError := func(err error) {
+		errs = append(errs, &RouteError{URL: routeURL(pathStrings), Source: route.Source, Err: err})
+	}
+
+	errorStatuses, err := parseErrorStatuses(route.Errors)
+	if err != nil {
+		pathError(e.ErrFailedAction("parse error statuses", err))
+	}
+	// generated servers match sentinels against one table, only the root
+	// declares them
+	if len(paths) > 0 {
+		if err := checkSentinelsOnRoot(errorStatuses); err != nil {
+			pathError(err)
+		}
+	}
+	// innermost mappings take precedence
+	errorsAcc := make([]*repr.ErrorStatus, 0, len(errorStatuses)+len(parentErrors))
+	errorsAcc = append(errorsAcc, errorStatuses...)
+	errorsAcc = append(errorsAcc, parentErrors...)
+
+	tags, err := parseTags(route.Tags)
 	if err != nil {
-		return nil, e.ErrFailedAction("parse endpoint", err)
+		pathError(e.ErrFailedAction("parse tags", err))
 	}
+	tagsAcc := appendTags(slices.Clone(parentTags), tags...)
+
+	endpoints, epErrs := parseEndpoints(route.Endpoints, pathStrings, errorsAcc, tagsAcc)
+	errs = append(errs, epErrs...)
+	errs = append(errs, redefinitionErrors(route, pathStrings)...)
 
 	middleware, err := parseMiddleware(route.Middleware)
 	if err != nil {
-		return nil, e.ErrFailedAction("parse middleware", err)
+		pathError(e.ErrFailedAction("parse middleware", err))
 	}
 
 	middlewareAcc := make(repr.Middlewares, 0, len(parentMiddleware)+len(middleware))
@@ -38,11 +109,14 @@ func traversePathsIter(route *http.Path, paths []*repr.PathString, parentMiddlew
 	middlewareAcc = append(middlewareAcc, middleware...)
 
 	subPaths := make([]*repr.Path, 0)
-	for _, p := range route.SubPaths {
-		path, err := traversePathsIter(p, pathStrings, middlewareAcc)
-		if err != nil {
-			return nil, err
-		}
+	children := route.SubPaths
+	if route.Type == http.PathWildcard && len(children) > 0 {
+		pathError(ErrWildcardNotLast(route.Name))
+		children = nil
+	}
+	for _, p := range children {
+		path, subErrs := traversePathsIter(p, pathStrings, middlewareAcc, errorsAcc, tagsAcc)
+		errs = append(errs, subErrs...)
 		subPaths = append(subPaths, path)
 	}
 
@@ -51,20 +125,98 @@ func traversePathsIter(route *http.Path, paths []*repr.PathString, parentMiddlew
 		Endpoints:  endpoints,
 		Middleware: middlewareAcc,
 		SubPath:    subPaths,
-	}, nil
+	}, errs
 }
 
-func parseEndpoints(httpEndpoints http.Endpoints, paths []*repr.PathString) ([]*repr.Endpoint, error) {
+func parseEndpoints(
+	httpEndpoints http.Endpoints,
+	paths []*repr.PathString,
+	pathErrors []*repr.ErrorStatus,
+	pathTags []string,
+) ([]*repr.Endpoint, RouteErrors) {
 	endpoints := make([]*repr.Endpoint, 0, len(httpEndpoints))
+	errs := make(RouteErrors, 0)
 
-	for method, ep := range httpEndpoints {
-		endpoint, err := parseHandler(ep, method, paths)
+	httpEndpoints, autoHead := withAutoHead(httpEndpoints)
+	for _, method := range httpEndpoints.Methods() {
+		ep := httpEndpoints[method]
+		endpoint, err := parseHandler(ep, method, paths, pathErrors, pathTags)
 		if err != nil {
-			return nil, ErrFnSignature(ep.Handler, err)
+			errs = append(errs, endpointErrors(ep, method, paths, err)...)
+			continue
+		}
+		if autoHead && method == http.HEAD {
+			// operation IDs are unique, the GET endpoint keeps its own
+			endpoint.OperationID = cmp.Or(endpoint.OperationID, endpoint.Handler.Name) + "Head"
+			endpoint.AutoHead = true
 		}
 		endpoints = append(endpoints, endpoint)
 	}
-	return endpoints, nil
+	return endpoints, errs
+}
+
+// withAutoHead adds the HEAD endpoint of a GET endpoint declared with
+// AutoHead, unless the path declares one.
+func withAutoHead(eps http.Endpoints) (http.Endpoints, bool) {
+	get, ok := eps[http.GET]
+	if _, declared := eps[http.HEAD]; !ok || !get.AutoHead || declared {
+		return eps, false
+	}
+	withHead := maps.Clone(eps)
+	get.AutoHead = false
+	withHead[http.HEAD] = get
+	return withHead, true
+}
+
+// endpointErrors locates the errors of parsing ep, one per offending field
+// of its request and response.
+func endpointErrors(ep http.Endpoint, method http.Method, paths []*repr.PathString, err error) RouteErrors {
+	handler := ""
+	if name, nameErr := getFnName(ep.Handler); nameErr == nil {
+		// method values are suffixed with -fm
+		handler = strings.TrimSuffix(string(name), "-fm")
+	}
+	routeError := func(field string, err error) *RouteError {
+		return &RouteError{
+			URL:     routeURL(paths),
+			Method:  method,
+			Handler: handler,
+			Source:  ep.Source,
+			Field:   field,
+			Err:     err,
+		}
+	}
+
+	var fields fieldErrors
+	if !errors.As(err, &fields) {
+		return RouteErrors{routeError("", err)}
+	}
+	errs := make(RouteErrors, 0, len(fields))
+	for _, fe := range fields {
+		errs = append(errs, routeError(strings.Join(fe.path, "."), fe.err))
+	}
+	return errs
+}
+
+// handlerName renders h like the runtime names functions.
+func handlerName(h *repr.Handler) string {
+	if h.Reciever == nil {
+		return h.Import + "." + h.Name
+	}
+	recv := h.Reciever.Name
+	if h.Reciever.Pointer {
+		recv = "(*" + recv + ")"
+	}
+	return h.Import + "." + recv + "." + h.Name
+}
+
+// routeURL renders paths for errors, parameters are shown as {name}.
+func routeURL(paths repr.PathStrings) string {
+	url, err := repr.PathToPattern(paths)
+	if err != nil || url == "" {
+		return "/"
+	}
+	return url
 }
 
 func parseMiddleware(middlewareFns []any) (repr.Middlewares, error) {
@@ -100,7 +252,20 @@ func parseFnIdents(fn any) (*repr.Handler, error) {
 var ctxInterface = reflect.TypeOf((*context.Context)(nil)).Elem()
 var errInterface = reflect.TypeOf((*error)(nil)).Elem()
 
-func parseHandler(ep http.Endpoint, method http.Method, paths []*repr.PathString) (*repr.Endpoint, error) {
+func parseHandler(
+	ep http.Endpoint,
+	method http.Method,
+	paths []*repr.PathString,
+	pathErrors []*repr.ErrorStatus,
+	pathTags []string,
+) (*repr.Endpoint, error) {
+	if method == "" || strings.ContainsFunc(string(method), func(r rune) bool { return r < 'A' || r > 'Z' }) {
+		return nil, ErrBadMethod(method)
+	}
+	if ep.AutoHead && method != http.GET {
+		return nil, ErrAutoHeadMethod(method)
+	}
+
 	fn := reflect.TypeOf(ep.Handler)
 	if fn.Kind() != reflect.Func {
 		return nil, e.ErrBadType(fn, "function")
@@ -113,17 +278,20 @@ func parseHandler(ep http.Endpoint, method http.Method, paths []*repr.PathString
 
 	reqType, resType, err := parseFnSignature(fn)
 	if err != nil {
-		return nil, e.ErrFailedAction("parse function signature", err)
+		return nil, ErrFnSignature(ep.Handler, e.ErrFailedAction("parse function signature", err))
 	}
 
-	body, err := parseData(reqType)
-	if err != nil {
-		return nil, e.ErrFailedActionWithItem("parse body", reqType.Name(), err)
+	body, bodyErr := parseData(reqType)
+	response, resErr := parseData(resType)
+	if bodyErr != nil || resErr != nil {
+		return nil, slices.Concat(
+			fieldErrorsOf("body", bodyErr),
+			fieldErrorsOf("response", resErr),
+		)
 	}
 
-	response, err := parseData(resType)
-	if err != nil {
-		return nil, e.ErrFailedActionWithItem("parse response", reqType.Name(), err)
+	if errs := checkPathParams(paths, body); len(errs) > 0 {
+		return nil, errs
 	}
 
 	// Parse endpoint-level middleware
@@ -132,6 +300,27 @@ func parseHandler(ep http.Endpoint, method http.Method, paths []*repr.PathString
 		return nil, e.ErrFailedAction("parse endpoint middleware", err)
 	}
 
+	epErrors, err := parseErrorStatuses(ep.Errors)
+	if err != nil {
+		return nil, e.ErrFailedAction("parse endpoint error statuses", err)
+	}
+	if err := checkSentinelsOnRoot(epErrors); err != nil {
+		return nil, err
+	}
+	epTags, err := parseTags(ep.Tags)
+	if err != nil {
+		return nil, e.ErrFailedAction("parse endpoint tags", err)
+	}
+
+	status, err := parseStatus(ep.Status, response)
+	if err != nil {
+		return nil, e.ErrFailedAction("parse status", err)
+	}
+
+	errorStatuses := make([]*repr.ErrorStatus, 0, len(epErrors)+len(pathErrors))
+	errorStatuses = append(errorStatuses, epErrors...)
+	errorStatuses = append(errorStatuses, pathErrors...)
+
 	return &repr.Endpoint{
 		Method: method,
 		Path:   paths,
@@ -142,6 +331,157 @@ func parseHandler(ep http.Endpoint, method http.Method, paths []*repr.PathString
 		Response:      response,
 		Handler:       handler,
 		Middleware:    epMiddleware,
+		Errors:        errorStatuses,
+		Tags:          appendTags(slices.Clone(pathTags), epTags...),
+		OperationID:   ep.OperationID,
+		Summary:       ep.Summary,
+		Deprecated:    ep.Deprecated,
+		Status:        status,
+		Source:        ep.Source,
+	}, nil
+}
+
+// parseStatus defaults the success status to 200, or 204 when the response
+// has no body.
+func parseStatus(status int, response *repr.Data) (int, error) {
+	if status == 0 {
+		if !response.HasBody() {
+			return 204, nil
+		}
+		return 200, nil
+	}
+	if status < 200 || status > 299 {
+		return 0, ErrBadSuccessStatus(status)
+	}
+	if !repr.StatusHasBody(status) && response.HasBody() {
+		return 0, ErrStatusWithBody(status)
+	}
+	return status, nil
+}
+
+// checkPathParams makes sure the parameters of the route and the path fields
+// of the request name each other, unmatched fields are taken to be misnamed
+// parameters in declaration order.
+func checkPathParams(paths []*repr.PathString, body *repr.Data) fieldErrors {
+	params := make([]string, 0)
+	wildcard := ""
+	for _, ps := range paths {
+		switch ps.Type {
+		case repr.PathPARAM:
+			params = append(params, ps.Name)
+		case repr.PathWILDCARD:
+			params = append(params, ps.Name)
+			wildcard = ps.Name
+		}
+	}
+	bound := make(map[string]bool)
+	unknown := make([]*repr.StructField, 0)
+	errs := make(fieldErrors, 0)
+	for _, field := range body.Fields {
+		if field.Serialization == nil || field.Serialization.Type != repr.SerializationPATH {
+			continue
+		}
+		if !slices.Contains(params, field.Serialization.Name) {
+			unknown = append(unknown, field)
+			continue
+		}
+		bound[field.Serialization.Name] = true
+		if field.Serialization.Name == wildcard {
+			field.Serialization.Wildcard = true
+			if field.Type != reflect.String {
+				errs = append(errs, &fieldError{path: []string{"body", field.Name}, err: ErrWildcardFieldType(wildcard, field.Type)})
+			}
+		}
+	}
+	missing := slices.DeleteFunc(params, func(param string) bool { return bound[param] })
+
+	for i, field := range unknown {
+		err := ErrPathFieldUnknown(field.Serialization.Name)
+		if i < len(missing) {
+			err = ErrPathFieldMisnamed(field.Serialization.Name, missing[i])
+		}
+		errs = append(errs, &fieldError{path: []string{"body", field.Name}, err: err})
+	}
+	for _, param := range missing[min(len(unknown), len(missing)):] {
+		errs = append(errs, &fieldError{path: []string{"body"}, err: ErrPathParamMissing(param)})
+	}
+	return errs
+}
+
+func parseTags(tags []string) ([]string, error) {
+	for _, tag := range tags {
+		if strings.TrimSpace(tag) == "" {
+			return nil, e.ErrFailedAction("parse tag", e.ErrNoValue)
+		}
+	}
+	return tags, nil
+}
+
+// appendTags appends the tags missing from tags.
+func appendTags(tags []string, more ...string) []string {
+	for _, tag := range more {
+		if !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+func parseErrorStatuses(statuses []http.ErrorStatus) ([]*repr.ErrorStatus, error) {
+	parsed := make([]*repr.ErrorStatus, 0, len(statuses))
+	for _, es := range statuses {
+		if es.Status < 400 || es.Status > 599 {
+			return nil, ErrBadStatus(es.Status)
+		}
+		switch {
+		case es.Err != nil:
+			if !reflect.TypeOf(es.Err).Comparable() {
+				return nil, e.ErrFailedActionWithItem("parse error status", es.Err.Error(), ErrErrorNotComparable)
+			}
+			parsed = append(parsed, &repr.ErrorStatus{
+				Status: es.Status,
+				Err:    es.Err,
+			})
+		case es.Type != nil:
+			errType, err := parseErrorType(es.Type)
+			if err != nil {
+				return nil, err
+			}
+			parsed = append(parsed, &repr.ErrorStatus{
+				Status: es.Status,
+				Type:   errType,
+			})
+		default:
+			return nil, e.ErrFailedActionWithItem("parse error status", "Err", e.ErrNoValue)
+		}
+	}
+	return parsed, nil
+}
+
+func checkSentinelsOnRoot(statuses []*repr.ErrorStatus) error {
+	for _, es := range statuses {
+		if es.Err != nil {
+			return ErrSentinelBelowRoot(es.Err)
+		}
+	}
+	return nil
+}
+
+func parseErrorType(t reflect.Type) (*repr.ErrorType, error) {
+	if !t.Implements(errInterface) {
+		return nil, e.ErrBadType(t, "error")
+	}
+	pointer := t.Kind() == reflect.Pointer
+	if pointer {
+		t = t.Elem()
+	}
+	if t.Name() == "" || t.PkgPath() == "" {
+		return nil, ErrErrorTypeIsAnon
+	}
+	return &repr.ErrorType{
+		Name:    t.Name(),
+		Import:  t.PkgPath(),
+		Pointer: pointer,
 	}, nil
 }
 
@@ -192,7 +532,7 @@ func parseData(s reflect.Type) (*repr.Data, error) {
 		return nil, FatalInvalidParam(s, reflect.Struct)
 	}
 
-	data, err := parseDataField(s)
+	data, err := parseDataField(s, make(map[reflect.Type]bool))
 	if err != nil {
 		return nil, err
 	}
@@ -227,32 +567,46 @@ func structFieldIter(s reflect.Type) iter.Seq[reflect.StructField] {
 	}
 }
 
-func parseDataField(s reflect.Type) (*repr.StructField, error) {
+// parseDataField describes s, visiting holds the named structs being parsed
+// so recursive types end in a reference instead of recursing forever.
+func parseDataField(s reflect.Type, visiting map[reflect.Type]bool) (*repr.StructField, error) {
 	T, err := extractFieldType(s)
 	if err != nil {
 		return nil, err
 	}
+	field, err := parseFieldType(T, visiting)
+	if err != nil {
+		return nil, err
+	}
+	field.Pointer = s.Kind() == reflect.Pointer
+	return field, nil
+}
+
+func parseFieldType(T reflect.Type, visiting map[reflect.Type]bool) (*repr.StructField, error) {
 	K := T.Kind()
 
+	if format, ok := scalarFormat(T); ok {
+		return parseScalar(T, format)
+	}
 	if slices.Contains(primitiveTypes, K) {
 		return parsePrimitive(T)
 	}
 
 	switch K {
 	case reflect.Array, reflect.Slice:
-		return parseArraySlice(T)
+		return parseArraySlice(T, visiting)
 	case reflect.Struct:
-		return parseStruct(T)
+		return parseStruct(T, visiting)
 	case reflect.Map:
-		return parseMap(T)
+		return parseMap(T, visiting)
 	default:
 		// Bad types are caught on type extraction
 		return nil, ErrFatalUnreachable
 	}
 }
 
-func parseArraySlice(s reflect.Type) (*repr.StructField, error) {
-	bf, err := parseDataField(s.Elem())
+func parseArraySlice(s reflect.Type, visiting map[reflect.Type]bool) (*repr.StructField, error) {
+	bf, err := parseDataField(s.Elem(), visiting)
 	if err != nil {
 		return nil, err
 	}
@@ -262,17 +616,34 @@ func parseArraySlice(s reflect.Type) (*repr.StructField, error) {
 	}, nil
 }
 
-func parseStruct(s reflect.Type) (*repr.StructField, error) {
+func parseStruct(s reflect.Type, visiting map[reflect.Type]bool) (*repr.StructField, error) {
+	if visiting[s] {
+		return &repr.StructField{
+			Name:     s.Name(),
+			Type:     reflect.Struct,
+			TypeName: s.Name(),
+			Import:   s.PkgPath(),
+			Ref:      true,
+		}, nil
+	}
+	if s.Name() != "" {
+		visiting[s] = true
+		defer delete(visiting, s)
+	}
+
 	dfs := make([]*repr.StructField, 0, s.NumField())
+	errs := make(fieldErrors, 0)
 	for f := range structFieldIter(s) {
 		serialization, validation, err := parseFieldTag(f)
 		if err != nil {
-			return nil, e.ErrFailedActionWithItem("parse field tag", f.Name, err)
+			errs = append(errs, fieldErrorsOf(f.Name, e.ErrFailedAction("parse field tag", err))...)
+			continue
 		}
 
-		df, err := parseDataField(f.Type)
+		df, err := parseDataField(f.Type, visiting)
 		if err != nil {
-			return nil, e.ErrFailedActionWithItem("parse data field", f.Name, err)
+			errs = append(errs, fieldErrorsOf(f.Name, err)...)
+			continue
 		}
 		dfs = append(dfs, &repr.StructField{
 			Name:          f.Name,
@@ -280,12 +651,22 @@ func parseStruct(s reflect.Type) (*repr.StructField, error) {
 			Validation:    validation,
 			Type:          df.Type,
 			SubFields:     df.SubFields,
+			TypeName:      df.TypeName,
+			Import:        df.Import,
+			Format:        df.Format,
+			Ref:           df.Ref,
+			Pointer:       df.Pointer,
 		})
 	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
 	return &repr.StructField{
 		Name:      s.Name(),
 		Type:      reflect.Struct,
 		SubFields: dfs,
+		TypeName:  s.Name(),
+		Import:    s.PkgPath(),
 	}, nil
 }
 
@@ -295,7 +676,20 @@ func parsePrimitive(s reflect.Type) (*repr.StructField, error) {
 	}, nil
 }
 
-func parseMap(s reflect.Type) (*repr.StructField, error) {
+// parseScalar describes types serialized as a single value, durations are
+// JSON encoded as integers and every other scalar as a string.
+func parseScalar(s reflect.Type, format string) (*repr.StructField, error) {
+	T := reflect.String
+	if s == durationType {
+		T = reflect.Int64
+	}
+	return &repr.StructField{
+		Type:   T,
+		Format: format,
+	}, nil
+}
+
+func parseMap(s reflect.Type, visiting map[reflect.Type]bool) (*repr.StructField, error) {
 	T := s.Key()
 	K := T.Kind()
 	if !slices.Contains(allowedMapKeyTypes, T.Kind()) {
@@ -303,7 +697,7 @@ func parseMap(s reflect.Type) (*repr.StructField, error) {
 	}
 
 	v := s.Elem()
-	vF, err := parseDataField(v)
+	vF, err := parseDataField(v, visiting)
 	if err != nil {
 		return nil, err
 	}
@@ -421,8 +815,9 @@ func parseValidation(s reflect.StructField) (
 	if val == "" {
 		return []string{}, nil // TODO(fati-kappe): fmt.Errorf("no validation tag found")
 	}
-	// TODO enforce only a subset of validation tags
-	// or verify correct validation tags
+	if err := checkValidation(val); err != nil {
+		return nil, err
+	}
 	return strings.Split(val, ","), nil
 }
 
@@ -435,6 +830,8 @@ func ParsePathString(path *http.Path) *repr.PathString {
 		t = repr.PathSTATIC
 	case http.PathParam:
 		t = repr.PathPARAM
+	case http.PathWildcard:
+		t = repr.PathWILDCARD
 	}
 	return &repr.PathString{Name: path.Name, Type: t}
 }
@@ -0,0 +1,559 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	e "github.com/simplicity-load/apispec/pkg/errors"
+	"github.com/simplicity-load/apispec/pkg/http"
+	repr "github.com/simplicity-load/apispec/pkg/repr/http"
+)
+
+type node struct {
+	Name     string `json:"name"`
+	Children []node `json:"children"`
+	Parent   *node  `json:"parent"`
+}
+
+type employee struct {
+	Name string `json:"name"`
+	Team *team  `json:"team"`
+}
+
+type team struct {
+	Members []employee `json:"members"`
+}
+
+type tree struct {
+	Root    node `json:"root"`
+	Sibling node `json:"sibling"`
+}
+
+func TestParseRecursiveTypes(t *testing.T) {
+	api := http.NewAPI()
+	api.Post(func(ctx context.Context, r *tree) (*employee, error) { return nil, nil }, "desc")
+
+	paths, err := ParsePaths(api)
+	if err != nil {
+		t.Fatalf("ParsePaths failed: %v", err)
+	}
+	endpoint := paths.Endpoints[0]
+
+	for _, root := range endpoint.Body.Fields {
+		if root.Ref || len(root.SubFields) != 3 {
+			t.Fatalf("%s must be described in full, got: %+v", root.Name, root)
+		}
+		children := root.SubFields[1].SubFields[0]
+		if !children.Ref || children.TypeName != "node" || len(children.SubFields) != 0 {
+			t.Errorf("%s.Children must reference node, got: %+v", root.Name, children)
+		}
+		if parent := root.SubFields[2]; !parent.Ref || parent.TypeName != "node" {
+			t.Errorf("%s.Parent must reference node, got: %+v", root.Name, parent)
+		}
+	}
+
+	teamField := endpoint.Response.Fields[1]
+	if teamField.Ref || teamField.TypeName != "team" {
+		t.Fatalf("Team must be described in full, got: %+v", teamField)
+	}
+	member := teamField.SubFields[0].SubFields[0]
+	if !member.Ref || member.TypeName != "employee" {
+		t.Errorf("Team.Members must reference employee, got: %+v", member)
+	}
+}
+
+type uuid [16]byte
+
+func (u uuid) MarshalText() ([]byte, error) { return nil, nil }
+
+type event struct {
+	ID      uuid          `json:"id" as:"id,path"`
+	At      time.Time     `json:"at"`
+	Until   *time.Time    `json:"until" as:"until,query"`
+	Timeout time.Duration `json:"timeout"`
+}
+
+func TestParseScalarTypes(t *testing.T) {
+	api := http.NewAPI()
+	api.Param("id").Get(func(ctx context.Context, r *event) (*event, error) { return nil, nil }, "desc")
+
+	paths, err := ParsePaths(api)
+	if err != nil {
+		t.Fatalf("ParsePaths failed: %v", err)
+	}
+	fields := paths.SubPath[0].Endpoints[0].Body.Fields
+	tests := []struct {
+		kind   reflect.Kind
+		format string
+	}{
+		{reflect.String, "uuid"},
+		{reflect.String, "date-time"},
+		{reflect.String, "date-time"},
+		{reflect.Int64, "duration"},
+	}
+	for i, tt := range tests {
+		field := fields[i]
+		if field.Type != tt.kind || field.Format != tt.format || len(field.SubFields) != 0 {
+			t.Errorf("%s: got: %s %q, wanted: %s %q", field.Name, field.Type, field.Format, tt.kind, tt.format)
+		}
+	}
+}
+
+type address struct {
+	Street string  `json:"street"`
+	Zip    float64 `json:"zip"`
+}
+
+type badUser struct {
+	Name    string  `json:"name"`
+	Address address `json:"address"`
+	Score   float32 `json:"score"`
+}
+
+type badResponse struct {
+	Untagged string
+}
+
+type validUser struct {
+	Name string `json:"name"`
+}
+
+func TestParseAggregatesErrors(t *testing.T) {
+	api := http.NewAPI()
+	users := api.Static("users")
+	users.Post(func(ctx context.Context, r *badUser) (*badResponse, error) { return nil, nil }, "desc")
+	users.Get(func(ctx context.Context, r *validUser) (*validUser, error) { return nil, nil }, "desc")
+	users.Param("id").Put(func(ctx context.Context, r validUser) (*validUser, error) { return nil, nil }, "desc")
+
+	_, err := ParsePaths(api)
+	var errs RouteErrors
+	if !errors.As(errors.Join(errors.New("other"), err), &errs) {
+		t.Fatalf("ParsePaths must fail with RouteErrors, got: %v", err)
+	}
+
+	want := []struct {
+		url    string
+		method http.Method
+		field  string
+	}{
+		{"/users", http.POST, "body.Address.Zip"},
+		{"/users", http.POST, "body.Score"},
+		{"/users", http.POST, "response.Untagged"},
+		{"/users/{id}", http.PUT, ""},
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("want %d errors, got %d:\n%v", len(want), len(errs), errs)
+	}
+	for i, w := range want {
+		got := errs[i]
+		if got.URL != w.url || got.Method != w.method || got.Field != w.field {
+			t.Errorf("error %d: want %s %s %s, got %s %s %s", i, w.method, w.url, w.field, got.Method, got.URL, got.Field)
+		}
+		if !strings.Contains(got.Handler, "TestParseAggregatesErrors") {
+			t.Errorf("error %d: handler must be named, got: %q", i, got.Handler)
+		}
+	}
+	if !errors.Is(err, ErrNoFloat) {
+		t.Errorf("RouteErrors must unwrap to the field errors")
+	}
+}
+
+type pathUser struct {
+	ID   string `json:"-" as:"id,path"`
+	Name string `json:"name"`
+}
+
+type pathTypo struct {
+	ID string `json:"-" as:"idd,path"`
+}
+
+type pathExtra struct {
+	ID   string `json:"-" as:"id,path"`
+	Slug string `json:"-" as:"slug,path"`
+}
+
+type pathSlug struct {
+	Slug string `json:"-" as:"slug,path"`
+}
+
+func TestParsePathParams(t *testing.T) {
+	api := http.NewAPI()
+	users := api.Static("users")
+	users.Get(func(ctx context.Context, r *pathUser) (*validUser, error) { return nil, nil }, "desc")
+	id := users.Param("id")
+	id.Get(func(ctx context.Context, r *pathUser) (*validUser, error) { return nil, nil }, "desc")
+	id.Put(func(ctx context.Context, r *pathTypo) (*validUser, error) { return nil, nil }, "desc")
+	id.Patch(func(ctx context.Context, r *pathExtra) (*validUser, error) { return nil, nil }, "desc")
+	id.Delete(func(ctx context.Context, r *validUser) (*validUser, error) { return nil, nil }, "desc")
+
+	_, err := ParsePaths(api)
+	var errs RouteErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("ParsePaths must fail with RouteErrors, got: %v", err)
+	}
+
+	want := []struct {
+		url    string
+		method http.Method
+		field  string
+		msg    string
+	}{
+		{"/users", http.GET, "body.ID", `path field "id" matches no route parameter`},
+		{"/users/{id}", http.PUT, "body.ID", `did you mean "id"`},
+		{"/users/{id}", http.PATCH, "body.Slug", `path field "slug" matches no route parameter`},
+		{"/users/{id}", http.DELETE, "body", `route parameter "id" isn't bound`},
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("want %d errors, got %d:\n%v", len(want), len(errs), errs)
+	}
+	for i, w := range want {
+		got := errs[i]
+		if got.URL != w.url || got.Method != w.method || got.Field != w.field || !strings.Contains(got.Err.Error(), w.msg) {
+			t.Errorf("error %d: want %s %s %s: %s, got: %v", i, w.method, w.url, w.field, w.msg, got)
+		}
+	}
+}
+
+func TestParseRouteConflicts(t *testing.T) {
+	list := func(ctx context.Context, r *validUser) (*validUser, error) { return nil, nil }
+	get := func(ctx context.Context, r *pathUser) (*validUser, error) { return nil, nil }
+	getSlug := func(ctx context.Context, r *pathSlug) (*validUser, error) { return nil, nil }
+
+	api := http.NewAPI()
+	users := api.Static("users")
+	users.Get(list, "desc")
+	users.Get(list, "desc")
+	users.Param("id").Get(get, "desc")
+	users.Static("me").Get(list, "desc")
+	api.Static("users").Param("slug").Delete(getSlug, "desc")
+	api.Param("id").Static("me").Put(get, "desc")
+	api.Static("users").Param("id").Put(get, "desc")
+
+	_, err := ParsePaths(api)
+	var errs RouteErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("ParsePaths must fail with RouteErrors, got: %v", err)
+	}
+
+	want := []struct {
+		url    string
+		method http.Method
+		msg    string
+	}{
+		{"/users", http.GET, "route already declared at"},
+		{"/users/{slug}", "", `route parameter "slug" conflicts with "id"`},
+		{"/users/me", http.GET, "route is shadowed by /users/{id}"},
+		{"/users/{id}", http.PUT, "route is ambiguous with /{id}/me"},
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("want %d errors, got %d:\n%v", len(want), len(errs), errs)
+	}
+	for i, w := range want {
+		got := errs[i]
+		if got.URL != w.url || got.Method != w.method || !strings.Contains(got.Err.Error(), w.msg) {
+			t.Errorf("error %d: want %s %s: %s, got: %v", i, w.method, w.url, w.msg, got)
+		}
+		if !strings.Contains(got.Source, "server_test.go:") || strings.Count(got.Error(), "server_test.go:") != 2 {
+			t.Errorf("error %d: must locate both declarations, got: %v", i, got)
+		}
+	}
+}
+
+type tagged struct {
+	Typo     string            `json:"typo" validate:"requierd"`
+	Param    int               `json:"param" validate:"min"`
+	NoParam  string            `json:"no_param" validate:"email=x"`
+	Keys     map[string]string `json:"keys" validate:"keys,endkeys"`
+	Empty    string            `json:"empty" validate:"required,"`
+	Custom   string            `json:"custom" validate:"required,slug|uuid"`
+	Valid    []string          `json:"valid" validate:"omitempty,max=3,dive,required,oneof=a b"`
+	ValidMap map[string]string `json:"valid_map" validate:"dive,keys,alpha,endkeys,min=1"`
+	Skipped  string            `json:"skipped" validate:"-"`
+}
+
+func TestParseValidation(t *testing.T) {
+	api := http.NewAPI()
+	api.Post(func(ctx context.Context, r *tagged) (*validUser, error) { return nil, nil }, "desc")
+
+	_, err := ParsePaths(api)
+	var errs RouteErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("ParsePaths must fail with RouteErrors, got: %v", err)
+	}
+	fields := make([]string, len(errs))
+	for i, err := range errs {
+		fields[i] = err.Field
+	}
+	want := []string{"body.Typo", "body.Param", "body.NoParam", "body.Keys", "body.Empty", "body.Custom"}
+	if !slices.Equal(fields, want) {
+		t.Fatalf("want errors on %v, got:\n%v", want, errs)
+	}
+
+	if err := RegisterValidation(ValidationRule{Name: "slug"}); err != nil {
+		t.Fatalf("RegisterValidation failed: %v", err)
+	}
+	t.Cleanup(func() {
+		validationRules.Lock()
+		defer validationRules.Unlock()
+		delete(validationRules.m, "slug")
+	})
+	if err := RegisterValidation(ValidationRule{Name: "slug"}); !errors.Is(err, ErrValidationExists) {
+		t.Errorf("expected ErrValidationExists, got: %v", err)
+	}
+	if err := RegisterValidation(ValidationRule{Name: "dive"}); !errors.Is(err, ErrValidationReserved) {
+		t.Errorf("expected ErrValidationReserved, got: %v", err)
+	}
+	_, err = ParsePaths(api)
+	if !errors.As(err, &errs) || len(errs) != len(want)-1 {
+		t.Errorf("registered rules must be accepted, got:\n%v", err)
+	}
+}
+
+func TestParseOperationMetadata(t *testing.T) {
+	list := func(ctx context.Context, r *validUser) (*validUser, error) { return nil, nil }
+	get := func(ctx context.Context, r *pathUser) (*validUser, error) { return nil, nil }
+
+	api := http.NewAPI()
+	api.Tag("api")
+	users := api.Static("users")
+	users.Tag("users", "api")
+	users.Get(list, "List users", http.OperationID("listUsers"), http.Summary("List"))
+	id := users.Param("id")
+	id.Get(get, "Get a user", http.Tags("read", "users"), http.Deprecated())
+
+	paths, err := ParsePaths(api)
+	if err != nil {
+		t.Fatalf("ParsePaths failed: %v", err)
+	}
+	usersPath := paths.SubPath[0]
+	listEp, getEp := usersPath.Endpoints[0], usersPath.SubPath[0].Endpoints[0]
+	if !slices.Equal(listEp.Tags, []string{"api", "users"}) ||
+		listEp.OperationID != "listUsers" || listEp.Summary != "List" || listEp.Deprecated {
+		t.Errorf("list metadata mismatch, got: %+v", listEp)
+	}
+	if !slices.Equal(getEp.Tags, []string{"api", "users", "read"}) || !getEp.Deprecated {
+		t.Errorf("get metadata mismatch, got: %+v", getEp)
+	}
+
+	id.Put(get, "Replace a user", http.OperationID("listUsers"))
+	users.Static("me").Tag(" ")
+	_, err = ParsePaths(api)
+	var errs RouteErrors
+	if !errors.As(err, &errs) || len(errs) != 2 {
+		t.Fatalf("want 2 RouteErrors, got: %v", err)
+	}
+	if errs[0].URL != "/users/me" || !errors.Is(errs[0], e.ErrNoValue) {
+		t.Errorf("blank tags must be rejected, got: %v", errs[0])
+	}
+	if errs[1].Method != http.PUT || !strings.Contains(errs[1].Error(), `operation ID "listUsers" already declared`) {
+		t.Errorf("duplicate operation IDs must be rejected, got: %v", errs[1])
+	}
+}
+
+type noContent struct{}
+
+func TestParseStatus(t *testing.T) {
+	get := func(ctx context.Context, r *validUser) (*validUser, error) { return nil, nil }
+	remove := func(ctx context.Context, r *pathUser) (*noContent, error) { return nil, nil }
+
+	api := http.NewAPI()
+	users := api.Static("users")
+	users.Get(get, "List users")
+	users.Post(get, "Create a user", http.Status(201))
+	users.Param("id").Delete(remove, "Delete a user")
+
+	paths, err := ParsePaths(api)
+	if err != nil {
+		t.Fatalf("ParsePaths failed: %v", err)
+	}
+	usersPath := paths.SubPath[0]
+	for i, want := range []int{200, 201} {
+		if got := usersPath.Endpoints[i].Status; got != want {
+			t.Errorf("%s status mismatch, got: %d, want: %d", usersPath.Endpoints[i].Method, got, want)
+		}
+	}
+	if got := usersPath.SubPath[0].Endpoints[0]; got.Status != 204 || got.HasResponseBody() {
+		t.Errorf("empty responses must default to 204, got: %d", got.Status)
+	}
+
+	for status, want := range map[int]string{
+		404: "required: 200 to 299",
+		204: "status 204 has no body",
+	} {
+		api := http.NewAPI()
+		api.Get(get, "List users", http.Status(status))
+		_, err := ParsePaths(api)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Status(%d): want error containing %q, got: %v", status, want, err)
+		}
+	}
+}
+
+type lockedError struct{}
+
+func (*lockedError) Error() string { return "locked" }
+
+func TestParseErrorStatuses(t *testing.T) {
+	get := func(ctx context.Context, r *validUser) (*validUser, error) { return nil, nil }
+	errNotFound := errors.New("not found")
+
+	api := http.NewAPI()
+	api.MapErrors(http.ErrorIs(errNotFound, 404))
+	users := api.Static("users")
+	users.MapErrors(http.ErrorAs[*lockedError](423))
+	users.Get(get, "List users", http.Errors(http.ErrorAs[*lockedError](409)))
+	paths, err := ParsePaths(api)
+	if err != nil {
+		t.Fatalf("ParsePaths failed: %v", err)
+	}
+	statuses := make([]int, 0)
+	for _, es := range paths.SubPath[0].Endpoints[0].Errors {
+		statuses = append(statuses, es.Status)
+	}
+	if !slices.Equal(statuses, []int{409, 423, 404}) {
+		t.Errorf("error statuses must list the innermost first, got: %v", statuses)
+	}
+
+	api = http.NewAPI()
+	users = api.Static("users")
+	users.MapErrors(http.ErrorIs(errNotFound, 404))
+	users.Post(get, "Create a user", http.Errors(http.ErrorIs(errNotFound, 409)))
+	_, err = ParsePaths(api)
+	var errs RouteErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("ParsePaths must fail with RouteErrors, got: %v", err)
+	}
+	want := []struct {
+		method http.Method
+		msg    string
+	}{
+		{"", "mapped with ErrorIs below the root"},
+		{http.POST, "mapped with ErrorIs below the root"},
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("want %d errors, got %d:\n%v", len(want), len(errs), errs)
+	}
+	for i, w := range want {
+		got := errs[i]
+		if got.URL != "/users" || got.Method != w.method || !strings.Contains(got.Err.Error(), w.msg) {
+			t.Errorf("error %d: want %s /users: %s, got: %v", i, w.method, w.msg, got)
+		}
+	}
+}
+
+func TestParseMethods(t *testing.T) {
+	list := func(ctx context.Context, r *validUser) (*validUser, error) { return nil, nil }
+	head := func(ctx context.Context, r *validUser) (*noContent, error) { return nil, nil }
+
+	api := http.NewAPI()
+	users := api.Static("users")
+	users.Get(list, "List users", http.OperationID("listUsers"), http.AutoHead())
+	users.Options(head, "Describe users")
+	users.Handle("PURGE", head, "Purge users")
+	me := users.Static("me")
+	me.Get(list, "Get me", http.AutoHead())
+	me.Head(head, "Check me")
+
+	paths, err := ParsePaths(api)
+	if err != nil {
+		t.Fatalf("ParsePaths failed: %v", err)
+	}
+	methods := func(endpoints []*repr.Endpoint) []http.Method {
+		methods := make([]http.Method, len(endpoints))
+		for i, ep := range endpoints {
+			methods[i] = ep.Method
+		}
+		return methods
+	}
+	usersPath := paths.SubPath[0]
+	if got, want := methods(usersPath.Endpoints), []http.Method{http.GET, http.HEAD, http.OPTIONS, "PURGE"}; !slices.Equal(got, want) {
+		t.Errorf("users methods mismatch, got: %v, want: %v", got, want)
+	}
+	derived := usersPath.Endpoints[1]
+	if !derived.AutoHead || derived.OperationID != "listUsersHead" || derived.HasResponseBody() {
+		t.Errorf("HEAD must be derived from GET without a body, got: %+v", derived)
+	}
+	meHead := usersPath.SubPath[0].Endpoints[1]
+	if meHead.Method != http.HEAD || meHead.AutoHead {
+		t.Errorf("declared HEAD endpoints take precedence, got: %+v", meHead)
+	}
+
+	for name, declare := range map[string]func(*http.Path){
+		"invalid method":           func(p *http.Path) { p.Handle("purge", head, "desc") },
+		"AutoHead declared on PUT": func(p *http.Path) { p.Put(list, "desc", http.AutoHead()) },
+	} {
+		api := http.NewAPI()
+		declare(api)
+		_, err := ParsePaths(api)
+		if err == nil || !strings.Contains(err.Error(), name) {
+			t.Errorf("want error containing %q, got: %v", name, err)
+		}
+	}
+}
+
+type wildcardFile struct {
+	Path string `json:"-" as:"path,path"`
+}
+
+type wildcardDepth struct {
+	Path int `json:"-" as:"path,path"`
+}
+
+func TestParseWildcards(t *testing.T) {
+	get := func(ctx context.Context, r *wildcardFile) (*validUser, error) { return nil, nil }
+	list := func(ctx context.Context, r *validUser) (*validUser, error) { return nil, nil }
+
+	api := http.NewAPI()
+	files := api.Static("files")
+	files.Static("index").Get(list, "desc")
+	files.Wildcard("path").Get(get, "desc")
+
+	paths, err := ParsePaths(api)
+	if err != nil {
+		t.Fatalf("ParsePaths failed: %v", err)
+	}
+	wildcard := paths.SubPath[0].SubPath[1]
+	if wildcard.Type != repr.PathWILDCARD {
+		t.Errorf("want a wildcard path, got: %s", wildcard.Type)
+	}
+	if field := wildcard.Endpoints[0].Body.Fields[0]; !field.Serialization.Wildcard {
+		t.Errorf("field must be bound to the wildcard, got: %+v", field.Serialization)
+	}
+
+	api = http.NewAPI()
+	files = api.Static("files")
+	files.Wildcard("path").Get(get, "desc")
+	files.Static("index").Get(list, "desc")
+	files.Wildcard("path").Static("raw").Get(get, "desc")
+	api.Static("depth").Wildcard("path").Get(func(ctx context.Context, r *wildcardDepth) (*validUser, error) { return nil, nil }, "desc")
+
+	_, err = ParsePaths(api)
+	var errs RouteErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("ParsePaths must fail with RouteErrors, got: %v", err)
+	}
+
+	want := []struct {
+		url    string
+		method http.Method
+		msg    string
+	}{
+		{"/files/{path...}", "", `paths declared under wildcard "path"`},
+		{"/depth/{path...}", http.GET, `wildcard "path" bound to a int field`},
+		{"/files/index", http.GET, "route is shadowed by /files/{path...}"},
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("want %d errors, got %d:\n%v", len(want), len(errs), errs)
+	}
+	for i, w := range want {
+		got := errs[i]
+		if got.URL != w.url || got.Method != w.method || !strings.Contains(got.Err.Error(), w.msg) {
+			t.Errorf("error %d: want %s %s: %s, got: %v", i, w.method, w.url, w.msg, got)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package server
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+
+	e "github.com/simplicity-load/apispec/pkg/errors"
+)
+
+// RuleParam tells whether a validation rule takes a parameter, as in
+// "min=3".
+type RuleParam int
+
+const (
+	RuleNoParam RuleParam = iota
+	RuleParamRequired
+	RuleParamOptional
+)
+
+// ValidationRule is a rule accepted in validate tags.
+type ValidationRule struct {
+	Name  string
+	Param RuleParam
+}
+
+var validationRules = struct {
+	sync.RWMutex
+	m map[string]ValidationRule
+}{m: make(map[string]ValidationRule)}
+
+// builtinRules are the rules of github.com/go-playground/validator.
+var builtinRules = map[RuleParam][]string{
+	RuleNoParam: {
+		// fields, "-" skips the field
+		"-", "required", "omitempty", "omitnil", "omitzero", "isdefault",
+		"structonly", "nostructlevel",
+		// strings
+		"alpha", "alphanum", "alphaunicode", "alphanumunicode", "ascii",
+		"printascii", "multibyte", "numeric", "number", "boolean",
+		"hexadecimal", "lowercase", "uppercase", "json", "jwt", "html",
+		"html_encoded", "url_encoded", "base32", "base64", "base64url",
+		"base64rawurl", "datauri", "semver", "cron", "cve", "ulid",
+		"md4", "md5", "sha256", "sha384", "sha512", "ripemd128",
+		"ripemd160", "tiger128", "tiger160", "tiger192", "mongodb",
+		"credit_card", "luhn_checksum", "ssn", "e164", "isbn", "isbn10",
+		"isbn13", "issn", "bic", "btc_addr", "btc_addr_bech32", "eth_addr",
+		"latitude", "longitude", "hexcolor", "rgb", "rgba", "hsl", "hsla",
+		"iscolor", "country_code", "iso3166_1_alpha2", "iso3166_1_alpha3",
+		"iso3166_1_alpha_numeric", "iso3166_2", "iso4217", "iso4217_numeric",
+		"bcp47_language_tag", "timezone",
+		// formats
+		"email", "url", "http_url", "uri", "urn_rfc2141", "file", "filepath",
+		"dir", "dirpath", "image", "uuid", "uuid3", "uuid4", "uuid5",
+		"uuid_rfc4122", "uuid3_rfc4122", "uuid4_rfc4122", "uuid5_rfc4122",
+		// networks
+		"ip", "ipv4", "ipv6", "ip_addr", "ip4_addr", "ip6_addr", "cidr",
+		"cidrv4", "cidrv6", "tcp_addr", "tcp4_addr", "tcp6_addr",
+		"udp_addr", "udp4_addr", "udp6_addr", "unix_addr", "mac",
+		"hostname", "hostname_rfc1123", "hostname_port", "fqdn",
+		"dns_rfc1035_label",
+	},
+	RuleParamRequired: {
+		// comparisons
+		"min", "max", "len", "eq", "ne", "gt", "gte", "lt", "lte",
+		"eq_ignore_case", "ne_ignore_case", "oneof", "oneofci",
+		// fields
+		"eqfield", "nefield", "gtfield", "gtefield", "ltfield", "ltefield",
+		"eqcsfield", "necsfield", "gtcsfield", "gtecsfield", "ltcsfield",
+		"ltecsfield", "fieldcontains", "fieldexcludes",
+		"required_if", "required_unless", "required_with",
+		"required_with_all", "required_without", "required_without_all",
+		"excluded_if", "excluded_unless", "excluded_with",
+		"excluded_with_all", "excluded_without", "excluded_without_all",
+		"skip_unless",
+		// strings
+		"contains", "containsany", "containsrune", "excludes",
+		"excludesall", "excludesrune", "startswith", "endswith",
+		"startsnotwith", "endsnotwith", "datetime",
+		"postcode_iso3166_alpha2", "postcode_iso3166_alpha2_field",
+	},
+	RuleParamOptional: {
+		"unique",
+	},
+}
+
+func init() {
+	for param, names := range builtinRules {
+		for _, name := range names {
+			if err := RegisterValidation(ValidationRule{Name: name, Param: param}); err != nil {
+				panic(err)
+			}
+		}
+	}
+}
+
+// RegisterValidation makes rule known to the parser, for custom validations
+// and aliases registered on the validator of the generated server.
+func RegisterValidation(rule ValidationRule) error {
+	if rule.Name == "" {
+		return e.ErrFailedAction("register validation", e.ErrNoValue)
+	}
+	if slices.Contains(validationKeywords, rule.Name) || strings.ContainsAny(rule.Name, ",|=") {
+		return e.ErrFailedActionWithItem("register validation", rule.Name, ErrValidationReserved)
+	}
+	validationRules.Lock()
+	defer validationRules.Unlock()
+	if _, ok := validationRules.m[rule.Name]; ok {
+		return e.ErrFailedActionWithItem("register validation", rule.Name, ErrValidationExists)
+	}
+	validationRules.m[rule.Name] = rule
+	return nil
+}
+
+var (
+	ErrValidationExists   = errors.New("validation rule already registered")
+	ErrValidationReserved = errors.New("validation rule name is reserved")
+)
+
+// validationKeywords structure the tag rather than validate, they can't be
+// combined with "|" nor take a parameter.
+var validationKeywords = []string{"dive", "keys", "endkeys"}
+
+// checkValidation parses the grammar of a validate tag: rules separated by
+// ",", alternatives by "|", parameters after "=", and the dive, keys and
+// endkeys keywords.
+func checkValidation(tag string) error {
+	inKeys := false
+	rules := strings.Split(tag, ",")
+	for i, rule := range rules {
+		switch rule {
+		case "dive":
+			continue
+		case "keys":
+			if i == 0 || rules[i-1] != "dive" {
+				return ErrBadValidation(rule, `"keys" must follow "dive"`)
+			}
+			inKeys = true
+			continue
+		case "endkeys":
+			if !inKeys {
+				return ErrBadValidation(rule, `"endkeys" without "keys"`)
+			}
+			inKeys = false
+			continue
+		}
+		for _, alternative := range strings.Split(rule, "|") {
+			if err := checkValidationRule(alternative); err != nil {
+				return err
+			}
+		}
+	}
+	if inKeys {
+		return ErrBadValidation("keys", `"keys" without "endkeys"`)
+	}
+	return nil
+}
+
+func checkValidationRule(rule string) error {
+	name, param, hasParam := strings.Cut(rule, "=")
+	if name == "" {
+		return ErrBadValidation(rule, "empty rule")
+	}
+	if slices.Contains(validationKeywords, name) {
+		return ErrBadValidation(rule, "keywords can't take parameters nor be alternatives")
+	}
+	validationRules.RLock()
+	known, ok := validationRules.m[name]
+	validationRules.RUnlock()
+	if !ok {
+		return ErrUnknownValidation(name)
+	}
+	switch {
+	case known.Param == RuleNoParam && hasParam:
+		return ErrBadValidation(rule, "takes no parameter")
+	case known.Param == RuleParamRequired && param == "":
+		return ErrBadValidation(rule, "parameter required")
+	}
+	return nil
+}
@@ -7,10 +7,21 @@ import (
 )
 
 func PathTypeToURL(path *PathString) (string, error) {
+	return pathTypeToString(path, ":"+path.Name, "*")
+}
+
+// PathTypeToPattern is like [PathTypeToURL] but renders parameters as
+// "{name}" and wildcards as "{name...}", the syntax of net/http's ServeMux.
+func PathTypeToPattern(path *PathString) (string, error) {
+	return pathTypeToString(path, "{"+path.Name+"}", "{"+path.Name+"...}")
+}
+
+func pathTypeToString(path *PathString, param, wildcard string) (string, error) {
 	url, ok := map[PathType]string{
-		PathROOT:   "",
-		PathPARAM:  ":" + path.Name,
-		PathSTATIC: path.Name,
+		PathROOT:     "",
+		PathPARAM:    param,
+		PathWILDCARD: wildcard,
+		PathSTATIC:   path.Name,
 	}[path.Type]
 	if !ok {
 		return "", e.ErrBadValueFromList("path type", path.Type, ValidPathTypes)
@@ -19,11 +30,21 @@ func PathTypeToURL(path *PathString) (string, error) {
 }
 
 func PathToURL(paths PathStrings) (string, error) {
+	return pathToString(paths, PathTypeToURL)
+}
+
+// PathToPattern is like [PathToURL] but renders parameters as "{name}" and
+// wildcards as "{name...}".
+func PathToPattern(paths PathStrings) (string, error) {
+	return pathToString(paths, PathTypeToPattern)
+}
+
+func pathToString(paths PathStrings, fn func(*PathString) (string, error)) (string, error) {
 	url := bytes.Buffer{}
 	for path := range paths.NoRootPaths() {
 		url.WriteRune('/')
 
-		s, err := PathTypeToURL(path)
+		s, err := fn(path)
 		if err != nil {
 			return "", err
 		}
@@ -30,6 +30,14 @@ func (endpoints Endpoints) Imports() iter.Seq[string] {
 					return
 				}
 			}
+			for _, es := range endpoint.Errors {
+				if es.Type == nil || es.Type.Import == "" {
+					continue
+				}
+				if !yield(es.Type.Import) {
+					return
+				}
+			}
 		}
 	}
 }
@@ -60,3 +68,44 @@ func (middleware Middlewares) Imports() iter.Seq[string] {
 		}
 	}
 }
+
+// AllEndpoints yields the endpoints of path and of all its subpaths, depth
+// first in declaration order.
+func (path *Path) AllEndpoints() iter.Seq[*Endpoint] {
+	return func(yield func(x *Endpoint) bool) {
+		path.allEndpoints(yield)
+	}
+}
+
+func (path *Path) allEndpoints(yield func(x *Endpoint) bool) bool {
+	for _, endpoint := range path.Endpoints {
+		if !yield(endpoint) {
+			return false
+		}
+	}
+	for _, subPath := range path.SubPath {
+		if !subPath.allEndpoints(yield) {
+			return false
+		}
+	}
+	return true
+}
+
+// DataImports yields the imports of the request and response types.
+func (endpoints Endpoints) DataImports() iter.Seq[string] {
+	return func(yield func(x string) bool) {
+		for _, endpoint := range endpoints {
+			for _, imp := range []string{
+				endpoint.Body.Import,
+				endpoint.Response.Import,
+			} {
+				if imp == "" {
+					continue
+				}
+				if !yield(imp) {
+					return
+				}
+			}
+		}
+	}
+}
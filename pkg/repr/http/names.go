@@ -0,0 +1,112 @@
+package http
+
+import (
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// TypeKey identifies a named Go type.
+type TypeKey struct {
+	Import string
+	Name   string
+}
+
+// UniqueTypeNames names every key uniquely. Names are kept when only one
+// type carries them, otherwise they're prefixed with their package name,
+// falling back to their full import path and then to a number, in sorted
+// order so names are stable between runs. Kept names are assigned first, so
+// prefixed names never take them. Characters outside of [A-Za-z0-9_], e.g.
+// of generic instantiations, are dropped.
+func UniqueTypeNames(keys []TypeKey) map[TypeKey]string {
+	keys = slices.Clone(keys)
+	slices.SortFunc(keys, func(a, b TypeKey) int {
+		if cmp := strings.Compare(a.Name, b.Name); cmp != 0 {
+			return cmp
+		}
+		return strings.Compare(a.Import, b.Import)
+	})
+	keys = slices.Compact(keys)
+
+	byName := make(map[string]int, len(keys))
+	for _, key := range keys {
+		byName[key.Name]++
+	}
+
+	names := make(map[TypeKey]string, len(keys))
+	taken := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		if name := Ident(key.Name); byName[key.Name] == 1 && !taken[name] {
+			taken[name] = true
+			names[key] = name
+		}
+	}
+	for _, key := range keys {
+		if _, ok := names[key]; ok {
+			continue
+		}
+		name := uniqueName(taken,
+			exportedIdent(path.Base(key.Import))+Ident(key.Name),
+			exportedIdent(key.Import)+Ident(key.Name))
+		taken[name] = true
+		names[key] = name
+	}
+	return names
+}
+
+// uniqueName returns the first candidate not taken, numbering the last one
+// when all are.
+func uniqueName(taken map[string]bool, candidates ...string) string {
+	for _, name := range candidates {
+		if !taken[name] {
+			return name
+		}
+	}
+	last := candidates[len(candidates)-1]
+	for i := 2; ; i++ {
+		if name := last + strconv.Itoa(i); !taken[name] {
+			return name
+		}
+	}
+}
+
+// Ident returns s when it's a valid identifier, otherwise see
+// [exportedIdent].
+func Ident(s string) string {
+	if isIdent(s) {
+		return s
+	}
+	return exportedIdent(s)
+}
+
+// exportedIdent turns s into an identifier starting with an upper case
+// letter, dropping every character that isn't a letter or digit and
+// capitalizing the one following it.
+func exportedIdent(s string) string {
+	b := strings.Builder{}
+	upper := true
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func isIdent(s string) bool {
+	for i, r := range s {
+		if r == '_' || unicode.IsLetter(r) || (i > 0 && unicode.IsDigit(r)) {
+			continue
+		}
+		return false
+	}
+	return len(s) > 0
+}
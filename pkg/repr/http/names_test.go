@@ -0,0 +1,39 @@
+package http
+
+import "testing"
+
+func TestUniqueTypeNames(t *testing.T) {
+	type test struct {
+		key  TypeKey
+		name string
+	}
+	tests := []test{
+		{TypeKey{Import: "example.com/users", Name: "User"}, "ExampleComUsersUser"},
+		{TypeKey{Import: "example.com/admin/users", Name: "User"}, "UsersUser"},
+		{TypeKey{Import: "example.com/admin", Name: "User"}, "AdminUser"},
+		{TypeKey{Import: "example.com/users", Name: "Address"}, "Address"},
+		{TypeKey{Import: "example.com/page", Name: "Page[example.com/users.User]"}, "PageExampleComUsersUser"},
+		// kept names aren't taken by prefixed ones, whichever sorts first
+		{TypeKey{Import: "example.com/api", Name: "User"}, "ExampleComApiUser2"},
+		{TypeKey{Import: "example.com/other", Name: "ApiUser"}, "ApiUser"},
+		{TypeKey{Import: "example.com/other", Name: "ExampleComApiUser"}, "ExampleComApiUser"},
+		{TypeKey{Import: "example.com/page", Name: "List[int]"}, "PageListInt"},
+		{TypeKey{Import: "example.com/other", Name: "ListInt"}, "ListInt"},
+	}
+	keys := make([]TypeKey, 0, len(tests))
+	for _, tt := range tests {
+		keys = append(keys, tt.key)
+	}
+	// names must not depend on the order keys are passed in
+	for range 2 {
+		names := UniqueTypeNames(keys)
+		for _, tt := range tests {
+			if names[tt.key] != tt.name {
+				t.Errorf("failed naming type: %+v, got: %s, wanted: %s", tt.key, names[tt.key], tt.name)
+			}
+		}
+		for i, j := 0, len(keys)-1; i < j; i, j = i+1, j-1 {
+			keys[i], keys[j] = keys[j], keys[i]
+		}
+	}
+}
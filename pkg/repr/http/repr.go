@@ -2,6 +2,7 @@ package http
 
 import (
 	"reflect"
+	"strings"
 
 	"github.com/simplicity-load/apispec/pkg/http"
 )
@@ -9,15 +10,17 @@ import (
 type PathType string
 
 const (
-	PathROOT   PathType = "ROOT"
-	PathSTATIC PathType = "STATIC"
-	PathPARAM  PathType = "PARAM"
+	PathROOT     PathType = "ROOT"
+	PathSTATIC   PathType = "STATIC"
+	PathPARAM    PathType = "PARAM"
+	PathWILDCARD PathType = "WILDCARD"
 )
 
 var ValidPathTypes = []PathType{
 	PathROOT,
 	PathSTATIC,
 	PathPARAM,
+	PathWILDCARD,
 }
 
 type PathString struct {
@@ -38,6 +41,46 @@ type Endpoint struct {
 	Response      *Data         `json:",omitempty"`
 	Handler       *Handler      `json:",omitempty"`
 	Middleware    Middlewares   `json:",omitempty"`
+	// Errors lists the error mappings of the endpoint, most specific first.
+	Errors []*ErrorStatus `json:",omitempty"`
+	// Tags lists the tags of the paths of the endpoint, outermost first, then
+	// its own.
+	Tags        []string `json:",omitempty"`
+	OperationID string   `json:",omitempty"`
+	Summary     string   `json:",omitempty"`
+	Deprecated  bool     `json:",omitempty"`
+	// Status is the status successful responses are answered with.
+	Status int `json:",omitempty"`
+	// AutoHead is set on HEAD endpoints derived from a GET endpoint declared
+	// with AutoHead.
+	AutoHead bool `json:",omitempty"`
+	// Source is the file:line the endpoint was declared at.
+	Source string `json:",omitempty"`
+}
+
+// ErrorStatus maps errors returned by a handler to an HTTP status code.
+type ErrorStatus struct {
+	Status int
+	// Err is the sentinel error matched with errors.Is.
+	Err error `json:"-"`
+	// Type is the error type matched with errors.As.
+	Type *ErrorType `json:",omitempty"`
+}
+
+// Description is the message of the sentinel error or the name of the error
+// type.
+func (es *ErrorStatus) Description() string {
+	if es.Type != nil {
+		return es.Type.Name
+	}
+	return es.Err.Error()
+}
+
+// ErrorType identifies a named error type, Pointer is set for pointers to it.
+type ErrorType struct {
+	Name    string
+	Import  string
+	Pointer bool
 }
 
 type Handler struct {
@@ -79,6 +122,18 @@ var ApiSpecSerializationTypes = []SerializationType{
 type Serialization struct {
 	Name string
 	Type SerializationType
+	// Wildcard marks the path field bound to the wildcard segment of the
+	// route.
+	Wildcard bool `json:",omitempty"`
+}
+
+// In names where the field is read from or written to, "body" for JSON and
+// the lowercased type otherwise, e.g. "query".
+func (s *Serialization) In() string {
+	if s.Type == SerializationJSON {
+		return "body"
+	}
+	return strings.ToLower(string(s.Type))
 }
 
 type StructField struct {
@@ -87,6 +142,26 @@ type StructField struct {
 	Serialization *Serialization `json:",omitempty"`
 	Validation    []string       `json:",omitempty"`
 	SubFields     []*StructField `json:",omitempty"`
+	// TypeName and Import identify named struct types, they're empty for
+	// anonymous structs and every other kind.
+	TypeName string `json:",omitempty"`
+	Import   string `json:",omitempty"`
+	// Format hints the representation of scalars, e.g. "date-time" for
+	// time.Time or "uuid" for types called UUID.
+	Format string `json:",omitempty"`
+	// Ref marks a recursive use of the named struct TypeName, its SubFields
+	// are only described by the enclosing field of the same type.
+	Ref bool `json:",omitempty"`
+	// Pointer marks fields declared as pointers, nil is encoded as null.
+	Pointer bool `json:",omitempty"`
+}
+
+// TypeKey identifies the named struct type of the field.
+func (sf *StructField) TypeKey() (TypeKey, bool) {
+	if sf.TypeName == "" {
+		return TypeKey{}, false
+	}
+	return TypeKey{Import: sf.Import, Name: sf.TypeName}, true
 }
 
 // Detailed information on an [Endpoint]'s request body or response
@@ -96,6 +171,36 @@ type Data struct {
 	Fields []*StructField
 }
 
+// TypeKey identifies the type of the data, anonymous structs have none.
+func (d *Data) TypeKey() (TypeKey, bool) {
+	if d.Name == "" {
+		return TypeKey{}, false
+	}
+	return TypeKey{Import: d.Import, Name: d.Name}, true
+}
+
+// HasBody tells whether any field of the data is serialized in the JSON body.
+func (d *Data) HasBody() bool {
+	for _, field := range d.Fields {
+		if field.Serialization != nil && field.Serialization.Type == SerializationJSON {
+			return true
+		}
+	}
+	return false
+}
+
+// StatusHasBody tells whether responses of status carry a body, 204 and 205
+// responses can't.
+func StatusHasBody(status int) bool {
+	return status != 204 && status != 205
+}
+
+// HasResponseBody tells whether successful responses of the endpoint carry a
+// body, responses to HEAD requests never do.
+func (ep *Endpoint) HasResponseBody() bool {
+	return ep.Method != http.HEAD && StatusHasBody(ep.Status)
+}
+
 type Middleware = Handler
 
 type Middlewares []*Middleware
@@ -110,10 +215,26 @@ type Path struct {
 type Endpoints []*Endpoint
 
 type Representation struct {
-	Routes         *Path
+	Routes *Path
+	// ErrorStatusMap maps the sentinel errors declared with ErrorIs to their
+	// status, error types declared with ErrorAs are only listed on the
+	// Errors of their endpoints.
 	ErrorStatusMap map[error]int
 }
 
+// NewRepresentation fills the ErrorStatusMap from the endpoints of routes.
+func NewRepresentation(routes *Path) Representation {
+	statuses := make(map[error]int)
+	for endpoint := range routes.AllEndpoints() {
+		for _, es := range endpoint.Errors {
+			if es.Err != nil {
+				statuses[es.Err] = es.Status
+			}
+		}
+	}
+	return Representation{Routes: routes, ErrorStatusMap: statuses}
+}
+
 // const a = `
 // 	paths: [
 // 		{